@@ -0,0 +1,251 @@
+// Package main provides the annotated source view of the go-yaml tool.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// span marks a range of the source with a label. Lines and columns are
+// 1-based, as shown in the token and event modes.
+type span struct {
+	startLine   int
+	startColumn int
+	endLine     int
+	endColumn   int
+	label       string
+}
+
+// ProcessAnnotatedTokens reads YAML from r and writes each source line to w
+// followed by markers under the tokens that start on it
+func ProcessAnnotatedTokens(r io.Reader, w io.Writer) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return &kindError{kind: KindIO, err: fmt.Errorf("failed to read input: %v", err)}
+	}
+	offsets := NewOffsetMap(input)
+
+	parser, err := yaml.NewParser(bytes.NewReader(input))
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %v", err)
+	}
+	defer parser.Close()
+
+	var spans []span
+	for {
+		yamlToken, err := parser.Next()
+		if err != nil {
+			// Show the tokens scanned so far along with the error
+			spans = append(spans, errorSpans(err)...)
+			if writeErr := writeAnnotated(w, input, spans); writeErr != nil {
+				return writeErr
+			}
+			return fmt.Errorf("failed to scan YAML: %w", err)
+		}
+		if yamlToken == nil {
+			break
+		}
+
+		token := newToken(yamlToken, offsets)
+		spans = append(spans, span{
+			startLine:   token.StartLine,
+			startColumn: token.StartColumn,
+			endLine:     token.EndLine,
+			endColumn:   token.EndColumn,
+			label:       spanLabel(token.Type, token.Value),
+		})
+	}
+
+	return writeAnnotated(w, input, spans)
+}
+
+// ProcessAnnotatedEvents reads YAML from r and writes each source line to w
+// followed by markers under the events that start on it
+func ProcessAnnotatedEvents(r io.Reader, w io.Writer) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return &kindError{kind: KindIO, err: fmt.Errorf("failed to read input: %v", err)}
+	}
+	offsets := NewOffsetMap(input)
+
+	parser, err := yaml.NewEventParser(bytes.NewReader(input))
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %v", err)
+	}
+	defer parser.Close()
+
+	var spans []span
+	for {
+		yamlEvent, err := parser.Next()
+		if err != nil {
+			// Show the events parsed so far along with the error
+			spans = append(spans, errorSpans(err)...)
+			if writeErr := writeAnnotated(w, input, spans); writeErr != nil {
+				return writeErr
+			}
+			return fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		if yamlEvent == nil {
+			break
+		}
+
+		event := newEvent(yamlEvent, offsets)
+		value := event.Value
+		if event.Type == EventAlias {
+			value = "*" + event.Anchor
+		}
+		spans = append(spans, span{
+			startLine:   event.StartLine,
+			startColumn: event.StartColumn,
+			endLine:     event.EndLine,
+			endColumn:   event.EndColumn,
+			label:       spanLabel(string(event.Type), value),
+		})
+	}
+
+	return writeAnnotated(w, input, spans)
+}
+
+// spanLabel names a token or event along with its value, if it has one
+func spanLabel(kind, value string) string {
+	if value == "" {
+		return kind
+	}
+	return kind + " " + fmt.Sprintf("%q", value)
+}
+
+// errorSpans marks where a scanner or parser error was found, and the
+// context it was found in
+func errorSpans(err error) []span {
+	var parserErr *yaml.ParserError
+	if !errors.As(err, &parserErr) || parserErr.Kind == "reader" {
+		return nil
+	}
+
+	spans := []span{{
+		startLine:   parserErr.ProblemLine,
+		startColumn: parserErr.ProblemCol + 1,
+		endLine:     parserErr.ProblemLine,
+		endColumn:   parserErr.ProblemCol + 1,
+		label:       "ERROR " + parserErr.Problem,
+	}}
+	if parserErr.Context != "" {
+		spans = append(spans, span{
+			startLine:   parserErr.ContextLine,
+			startColumn: parserErr.ContextCol + 1,
+			endLine:     parserErr.ContextLine,
+			endColumn:   parserErr.ContextCol + 1,
+			label:       "CONTEXT " + parserErr.Context,
+		})
+	}
+	return spans
+}
+
+// writeAnnotated writes every source line followed by one marker line for
+// each span that starts on it. Spans that continue on later lines are
+// marked up to the end of their first line.
+func writeAnnotated(w io.Writer, input []byte, spans []span) error {
+	lines := sourceLines(input)
+
+	// Spans past the last line, such as the end of the stream after a
+	// final line break, are shown on an empty line of their own
+	lastLine := len(lines)
+	for _, s := range spans {
+		if s.startLine > lastLine {
+			lastLine = s.startLine
+		}
+	}
+
+	var buf bytes.Buffer
+	for line := 1; line <= lastLine; line++ {
+		var text []rune
+		if line <= len(lines) {
+			text = []rune(lines[line-1])
+		}
+		if len(text) > 0 {
+			fmt.Fprintf(&buf, "%4d | %s\n", line, string(text))
+		} else {
+			fmt.Fprintf(&buf, "%4d |\n", line)
+		}
+
+		for _, s := range spans {
+			if s.startLine != line {
+				continue
+			}
+			pad := snippetPad(string(text), s.startColumn)
+
+			end := s.endColumn
+			label := s.label
+			if s.endLine > s.startLine {
+				end = len(text) + 1
+				label += fmt.Sprintf(" (to %d;%d)", s.endLine, s.endColumn)
+			}
+			width := columnsWidth(text, s.startColumn, end)
+			if width < 1 {
+				width = 1
+			}
+			fmt.Fprintf(&buf, "     | %s%s %s\n", pad, strings.Repeat("^", width), label)
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// columnsWidth returns the display width of the columns of text from start
+// up to end, so that wide characters get a caret for each column they take.
+// Columns past the end of the text count one each.
+func columnsWidth(text []rune, start, end int) int {
+	width := 0
+	for column := start; column < end; column++ {
+		if column >= 1 && column <= len(text) {
+			width += runeWidth(text[column-1])
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// sourceLines decodes the input the way go-yaml does and splits it into
+// lines. A final line break does not start another line.
+func sourceLines(input []byte) []string {
+	var text string
+	switch {
+	case bytes.HasPrefix(input, []byte{0xFF, 0xFE}):
+		text = decodeUTF16(input[2:], false)
+	case bytes.HasPrefix(input, []byte{0xFE, 0xFF}):
+		text = decodeUTF16(input[2:], true)
+	default:
+		text = string(bytes.TrimPrefix(input, []byte{0xEF, 0xBB, 0xBF}))
+	}
+
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// decodeUTF16 converts UTF-16 input without its byte order mark to a string
+func decodeUTF16(input []byte, bigEndian bool) string {
+	units := make([]uint16, 0, len(input)/2)
+	for i := 0; i+1 < len(input); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(input[i])<<8|uint16(input[i+1]))
+		} else {
+			units = append(units, uint16(input[i+1])<<8|uint16(input[i]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestAnnotatedTokens tests that token spans are marked under the source
+// line they start on
+func TestAnnotatedTokens(t *testing.T) {
+	stdout, stderr, err := runCommand("key: [a, bc]\n", "tokens", "-a")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no stderr, got %q", stderr)
+	}
+	expected := "   1 | key: [a, bc]\n" +
+		"     | ^ STREAM-START\n" +
+		"     | ^ BLOCK-MAPPING-START\n" +
+		"     | ^ KEY\n" +
+		"     | ^^^ SCALAR \"key\"\n" +
+		"     |    ^ VALUE\n" +
+		"     |      ^ FLOW-SEQUENCE-START\n" +
+		"     |       ^ SCALAR \"a\"\n" +
+		"     |        ^ FLOW-ENTRY\n" +
+		"     |          ^^ SCALAR \"bc\"\n" +
+		"     |            ^ FLOW-SEQUENCE-END\n" +
+		"   2 |\n" +
+		"     | ^ BLOCK-END\n" +
+		"     | ^ STREAM-END\n"
+	if stdout != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout)
+	}
+}
+
+// TestAnnotatedEvents tests multi-line spans and tab alignment in the
+// annotated event view
+func TestAnnotatedEvents(t *testing.T) {
+	stdout, _, err := runCommand("a: &z |\n  x\n  y\nb:\t*z\n", "-e", "--annotate")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := []string{
+		"   1 | a: &z |\n     | ^ STREAM-START\n",
+		"     |    ^^^^ SCALAR \"x\\ny\\n\" (to 4;1)\n",
+		"   4 | b:\t*z\n     | ^ SCALAR \"b\"\n     |   \t^^ ALIAS \"*z\"\n",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+}
+
+// TestAnnotatedWideCharacters tests that wide characters get a caret for
+// each column they take, and push the spans after them along
+func TestAnnotatedWideCharacters(t *testing.T) {
+	stdout, _, err := runCommand("b: [日本, x]\n", "tokens", "-a")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := "     |     ^^^^ SCALAR \"日本\"\n" +
+		"     |         ^ FLOW-ENTRY\n" +
+		"     |           ^ SCALAR \"x\"\n"
+	if !strings.Contains(stdout, expected) {
+		t.Errorf("Expected output to contain %q, got %q", expected, stdout)
+	}
+}
+
+// TestAnnotatedError tests that scanner errors are marked where they were
+// found
+func TestAnnotatedError(t *testing.T) {
+	stdout, _, err := runCommand("a: b\n  c: d\n", "-t", "-a")
+	if err == nil {
+		t.Errorf("Expected an error, got none")
+	}
+	expected := "   2 |   c: d\n     |    ^ ERROR mapping values are not allowed in this context\n"
+	if !strings.Contains(stdout, expected) {
+		t.Errorf("Expected output to contain %q, got %q", expected, stdout)
+	}
+}
@@ -0,0 +1,67 @@
+// Package main provides formatting check utilities for the go-yaml tool.
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// compareFormatted compares an input with the YAML re-emitted from it. With
+// diff the changes are written to w as a unified diff, and with check a
+// change is returned as a format failure pointing at the first changed line.
+func compareFormatted(w io.Writer, input, formatted []byte, file string, check, diff bool) *Failure {
+	if string(input) == string(formatted) {
+		return nil
+	}
+
+	name := file
+	if name == "" {
+		name = "<stdin>"
+	}
+	if diff {
+		fmt.Fprint(w, unifiedDiff(name+".orig", name, string(input), string(formatted)))
+	}
+	if !check {
+		return nil
+	}
+
+	changed := changedLines(input, formatted)
+	message := fmt.Sprintf("not formatted as go-yaml writes it (%d lines differ)", changed)
+	if changed == 1 {
+		message = "not formatted as go-yaml writes it (1 line differs)"
+	}
+	return &Failure{
+		Kind:    KindFormat,
+		Message: message,
+		File:    file,
+		Line:    firstChangedLine(input, formatted),
+	}
+}
+
+// firstChangedLine returns the 1-based line of input where formatted first
+// differs from it
+func firstChangedLine(input, formatted []byte) int {
+	a, b := splitLines(string(input)), splitLines(string(formatted))
+	for i := range a {
+		if i >= len(b) || a[i] != b[i] {
+			return i + 1
+		}
+	}
+	// The input is a prefix of the formatted text, so the change comes after
+	// its last line
+	return len(a)
+}
+
+// changedLines counts the lines between the first and the last line where
+// formatted differs from input. Those lines are replaced rather than added
+// to, so it counts the larger of the two sides.
+func changedLines(input, formatted []byte) int {
+	a, b := splitLines(string(input)), splitLines(string(formatted))
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	return max(len(a), len(b))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheck tests that --check fails only for files that re-emitting would
+// change, and names the first changed line
+func TestCheck(t *testing.T) {
+	dir := writeInputFiles(t, map[string]string{
+		"good.yaml": "b: 1\na: [1, 2]\n",
+		"bad.yaml":  "b: 1\na:   [1,2]\n",
+	})
+	good := filepath.Join(dir, "good.yaml")
+	bad := filepath.Join(dir, "bad.yaml")
+
+	stdout, stderr, err := runCommand("", "-Y", "--check", good)
+	if err != nil {
+		t.Errorf("Expected no error, got %v: %s", err, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("Expected no output, got %q", stdout)
+	}
+
+	stdout, stderr, err = runCommand("", "yaml", "--preserve", "--check", good, bad)
+	if code := exitCode(err); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if stdout != "" {
+		t.Errorf("Expected no output, got %q", stdout)
+	}
+	expected := "Error: " + bad + ":2: format error in document 0: not formatted as go-yaml writes it (1 line differs)\n  a:   [1,2]\n"
+	if stderr != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr)
+	}
+}
+
+// TestDiff tests the unified diff of --diff, alone and with --check
+func TestDiff(t *testing.T) {
+	input := "b: 1\na:   [1,2]\n"
+	expected := "--- <stdin>.orig\n+++ <stdin>\n@@ -1,2 +1,4 @@\n+a:\n+  - 1\n+  - 2\n b: 1\n-a:   [1,2]\n"
+
+	stdout, stderr, err := runCommand(input, "-y", "--diff")
+	if err != nil {
+		t.Errorf("Expected no error, got %v: %s", err, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout)
+	}
+
+	stdout, _, err = runCommand(input, "yaml", "--check", "--diff")
+	if code := exitCode(err); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if stdout != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout)
+	}
+
+	_, stderr, err = runCommand(input, "-j", "--diff")
+	if code := exitCode(err); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(stderr, "need -y, -Y or --normalize") {
+		t.Errorf("Expected usage error, got %q", stderr)
+	}
+}
@@ -0,0 +1,218 @@
+// Package main provides line diff utilities for the go-yaml tool.
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffLine is one line of an edit script: ' ' kept, '-' removed or '+'
+// added
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// splitLines splits text into lines that keep their line breaks, so that
+// a missing final line break shows up as a difference
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// unifiedDiff returns the differences between two texts in unified diff
+// format, or "" when they are equal
+func unifiedDiff(aName, bName, a, b string) string {
+	script := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	aLine, bLine := 1, 1
+	for i := 0; i < len(script); {
+		if script[i].op == ' ' {
+			aLine++
+			bLine++
+			i++
+			continue
+		}
+
+		// A hunk runs from the context before this change to the context
+		// after the last change that is close enough to join it
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(script) && j <= end+2*diffContext; j++ {
+			if script[j].op != ' ' {
+				end = j
+			}
+		}
+		stop := end + diffContext + 1
+		if stop > len(script) {
+			stop = len(script)
+		}
+
+		aStart, bStart := aLine-(i-start), bLine-(i-start)
+		aCount, bCount := 0, 0
+		for _, line := range script[start:stop] {
+			if line.op != '+' {
+				aCount++
+			}
+			if line.op != '-' {
+				bCount++
+			}
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, line := range script[start:stop] {
+			out.WriteByte(line.op)
+			out.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		for _, line := range script[i:stop] {
+			if line.op != '+' {
+				aLine++
+			}
+			if line.op != '-' {
+				bLine++
+			}
+		}
+		i = stop
+	}
+	return out.String()
+}
+
+// hunkRange formats the start and length of a hunk. An empty range starts
+// at the line before it, and a length of one is left out.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// diffLines returns the shortest edit script that turns a into b, using
+// the linear space variant of Myers' algorithm: each step finds the middle
+// snake of an optimal path and splits the problem around it
+func diffLines(a, b []string) []diffLine {
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return d.script
+}
+
+// differ holds the texts being compared and the edit script built so far
+type differ struct {
+	a, b   []string
+	script []diffLine
+}
+
+// compare appends the edit script that turns a[aLo:aHi] into b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// Lines shared at the start and the end are kept as they are
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.script = append(d.script, diffLine{' ', d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for _, line := range d.b[bLo:bHi] {
+			d.script = append(d.script, diffLine{'+', line})
+		}
+	case bLo == bHi:
+		for _, line := range d.a[aLo:aHi] {
+			d.script = append(d.script, diffLine{'-', line})
+		}
+	default:
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for _, line := range d.a[x:u] {
+			d.script = append(d.script, diffLine{' ', line})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for _, line := range d.a[aHi : aHi+suffix] {
+		d.script = append(d.script, diffLine{' ', line})
+	}
+}
+
+// middleSnake runs the search for the shortest edit script of a[aLo:aHi]
+// and b[bLo:bHi] from both ends at once, and returns the snake where the two
+// searches meet, from (x, y) to (u, v). Only the furthest point on each
+// diagonal is kept, so the search needs space for the diagonals alone.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	// forward holds the furthest x reached on each diagonal k = x-y, and
+	// backward the same for the reversed texts
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for step := 0; step <= limit; step++ {
+		for k := -step; k <= step; k += 2 {
+			var sx int
+			if k == -step || k != step && forward[offset+k-1] < forward[offset+k+1] {
+				sx = forward[offset+k+1]
+			} else {
+				sx = forward[offset+k-1] + 1
+			}
+			sy := sx - k
+			ex, ey := sx, sy
+			for ex < n && ey < m && d.a[aLo+ex] == d.b[bLo+ey] {
+				ex++
+				ey++
+			}
+			forward[offset+k] = ex
+			if kr := delta - k; odd && kr >= -(step-1) && kr <= step-1 && ex+backward[offset+kr] >= n {
+				return aLo + sx, bLo + sy, aLo + ex, bLo + ey
+			}
+		}
+
+		for k := -step; k <= step; k += 2 {
+			var sx int
+			if k == -step || k != step && backward[offset+k-1] < backward[offset+k+1] {
+				sx = backward[offset+k+1]
+			} else {
+				sx = backward[offset+k-1] + 1
+			}
+			sy := sx - k
+			ex, ey := sx, sy
+			for ex < n && ey < m && d.a[aHi-ex-1] == d.b[bHi-ey-1] {
+				ex++
+				ey++
+			}
+			backward[offset+k] = ex
+			if kf := delta - k; !odd && kf >= -step && kf <= step && ex+forward[offset+kf] >= n {
+				return aHi - ex, bHi - ey, aHi - sx, bHi - sy
+			}
+		}
+	}
+	// The searches always meet within limit steps
+	panic("diff: no middle snake")
+}
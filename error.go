@@ -0,0 +1,93 @@
+// Package main provides parse error reporting utilities for the go-yaml tool.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ErrorInfo represents a scanner or parser error for YAML encoding
+type ErrorInfo struct {
+	Error      string `yaml:"Error"`
+	Problem    string `yaml:"Problem"`
+	Context    string `yaml:"Context,omitempty"`
+	Pos        string `yaml:"Pos,omitempty"`
+	ContextPos string `yaml:"ContextPos,omitempty"`
+	Offset     string `yaml:"Offset,omitempty"`
+}
+
+// formatErrorInfo converts a parser error into an ErrorInfo struct.
+// It returns nil if err did not come from the go-yaml reader, scanner or
+// parser.
+func formatErrorInfo(err error, offsets *OffsetMap) *ErrorInfo {
+	var parserErr *yaml.ParserError
+	if !errors.As(err, &parserErr) {
+		return nil
+	}
+
+	info := &ErrorInfo{
+		Error:   parserErr.Kind,
+		Problem: parserErr.Problem,
+		Context: parserErr.Context,
+	}
+	if parserErr.Kind == "reader" {
+		info.Offset = fmt.Sprintf("%d", parserErr.Offset)
+		return info
+	}
+	info.Pos = fmt.Sprintf("%d;%d", parserErr.ProblemLine, parserErr.ProblemCol+1)
+	info.Offset = fmt.Sprintf("%d", offsets.ByteOffset(parserErr.ProblemIndex))
+	if parserErr.Context != "" {
+		info.ContextPos = fmt.Sprintf("%d;%d", parserErr.ContextLine, parserErr.ContextCol+1)
+	}
+
+	return info
+}
+
+// printErrorInfo writes an error record as the final item of a token or
+// event sequence
+func printErrorInfo(w io.Writer, info *ErrorInfo, compact bool, style YAMLStyle) error {
+	var buf bytes.Buffer
+	enc := newYAMLEncoder(&buf, style)
+
+	if compact {
+		// For compact mode, output the error as a flow style mapping in a sequence
+		compactNode := &yaml.Node{
+			Kind:  yaml.MappingNode,
+			Style: yaml.FlowStyle,
+		}
+		fields := []struct{ key, value string }{
+			{"Error", info.Error},
+			{"Problem", info.Problem},
+			{"Context", info.Context},
+			{"Pos", info.Pos},
+			{"ContextPos", info.ContextPos},
+			{"Offset", info.Offset},
+		}
+		for _, field := range fields {
+			if field.value == "" {
+				continue
+			}
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: field.key},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: field.value})
+		}
+
+		if err := enc.Encode([]*yaml.Node{compactNode}); err != nil {
+			enc.Close()
+			return &kindError{kind: KindEncode, err: fmt.Errorf("failed to marshal compact error info: %v", err)}
+		}
+	} else {
+		if err := enc.Encode([]*ErrorInfo{info}); err != nil {
+			enc.Close()
+			return &kindError{kind: KindEncode, err: fmt.Errorf("failed to marshal error info: %v", err)}
+		}
+	}
+	enc.Close()
+	fmt.Fprint(w, buf.String())
+
+	return nil
+}
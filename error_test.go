@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPartialOutputOnError tests that tokens and events produced before a
+// parse error are printed, followed by an error record
+func TestPartialOutputOnError(t *testing.T) {
+	invalidYAML := "a: 1\nb: 2\n  c: 3\n"
+
+	tests := []struct {
+		name     string
+		flags    []string
+		expected []string
+	}{
+		{
+			"token mode",
+			[]string{"-t"},
+			[]string{"{Token: SCALAR, Value: a}", "{Token: SCALAR, Value: b}", "{Error: scanner, Problem: mapping values are not allowed in this context, Pos: 3;4, Offset: 13}"},
+		},
+		{
+			"token profuse long mode",
+			[]string{"-T", "-l"},
+			[]string{"Value: b", "- Error: scanner", "  Problem: mapping values are not allowed in this context", "  Pos: 3;4"},
+		},
+		{
+			"event mode",
+			[]string{"-e"},
+			[]string{"{Event: SCALAR, Value: a}", "{Event: SCALAR, Value: 1}", "{Error: scanner, Problem: mapping values are not allowed in this context, Pos: 3;4, Offset: 13}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCommand(invalidYAML, tt.flags...)
+			if err == nil {
+				t.Errorf("Expected error, got none")
+			}
+			if stderr == "" {
+				t.Errorf("Expected error message on stderr")
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(stdout, expected) {
+					t.Errorf("Expected output to contain %q, got %q", expected, stdout)
+				}
+			}
+		})
+	}
+}
+
+// TestParserErrorContext tests that parser errors include their context
+func TestParserErrorContext(t *testing.T) {
+	stdout, _, err := runCommand("- a\nb: c\n", "-e")
+	if err == nil {
+		t.Errorf("Expected error, got none")
+	}
+	expected := []string{
+		"{Event: SEQUENCE-START}",
+		"Error: parser",
+		"Problem: did not find expected '-' indicator",
+		"Context: while parsing a block collection",
+		"ContextPos: 1;1",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
 	"go.yaml.in/yaml/v3"
@@ -15,297 +14,271 @@ import (
 type EventType string
 
 const (
+	EventStreamStart   EventType = "STREAM-START"
+	EventStreamEnd     EventType = "STREAM-END"
 	EventDocumentStart EventType = "DOCUMENT-START"
 	EventDocumentEnd   EventType = "DOCUMENT-END"
+	EventAlias         EventType = "ALIAS"
 	EventScalar        EventType = "SCALAR"
 	EventSequenceStart EventType = "SEQUENCE-START"
 	EventSequenceEnd   EventType = "SEQUENCE-END"
 	EventMappingStart  EventType = "MAPPING-START"
 	EventMappingEnd    EventType = "MAPPING-END"
+	EventTailComment   EventType = "TAIL-COMMENT"
 )
 
-// Event represents a YAML event
+// Event represents a YAML event produced by the go-yaml parser
 type Event struct {
-	Type        EventType
-	Value       string
-	Anchor      string
-	Tag         string
-	Style       string
-	Implicit    bool
-	StartLine   int
-	StartColumn int
-	EndLine     int
-	EndColumn   int
-	HeadComment string
-	LineComment string
-	FootComment string
+	Type           EventType
+	Value          string
+	Anchor         string
+	Tag            string
+	Style          string
+	Implicit       bool
+	QuotedImplicit bool
+	Version        string
+	TagDirectives  []string
+	StartLine      int
+	StartColumn    int
+	StartIndex     int
+	StartOffset    int
+	EndLine        int
+	EndColumn      int
+	EndIndex       int
+	EndOffset      int
+	HeadComment    string
+	LineComment    string
+	FootComment    string
 }
 
 // EventInfo represents the information about a YAML event for YAML encoding
 type EventInfo struct {
-	Event  string `yaml:"Event"`
-	Value  string `yaml:"Value,omitempty"`
-	Style  string `yaml:"Style,omitempty"`
-	Tag    string `yaml:"Tag,omitempty"`
-	Anchor string `yaml:"Anchor,omitempty"`
-	Head   string `yaml:"Head,omitempty"`
-	Line   string `yaml:"Line,omitempty"`
-	Foot   string `yaml:"Foot,omitempty"`
-	Pos    string `yaml:"Pos,omitempty"`
+	Event    string   `yaml:"Event"`
+	Value    string   `yaml:"Value,omitempty"`
+	Style    string   `yaml:"Style,omitempty"`
+	Tag      string   `yaml:"Tag,omitempty"`
+	Anchor   string   `yaml:"Anchor,omitempty"`
+	Implicit string   `yaml:"Implicit,omitempty"`
+	Version  string   `yaml:"Version,omitempty"`
+	Tags     []string `yaml:"Tags,omitempty,flow"`
+	Head     string   `yaml:"Head,omitempty"`
+	Line     string   `yaml:"Line,omitempty"`
+	Foot     string   `yaml:"Foot,omitempty"`
+	Pos      string   `yaml:"Pos,omitempty"`
+	Offset   string   `yaml:"Offset,omitempty"`
+	Index    string   `yaml:"Index,omitempty"`
 }
 
-// ProcessEvents reads YAML from stdin and outputs event information
-func ProcessEvents(profuse, compact bool) error {
-	decoder := yaml.NewDecoder(os.Stdin)
-	firstDoc := true
+// ProcessEvents reads YAML from r and writes event information to w using the internal parser
+func ProcessEvents(r io.Reader, w io.Writer, profuse, compact bool, style YAMLStyle) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return &kindError{kind: KindIO, err: fmt.Errorf("failed to read input: %v", err)}
+	}
+	offsets := NewOffsetMap(input)
+
+	parser, err := yaml.NewEventParser(bytes.NewReader(input))
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %v", err)
+	}
+	defer parser.Close()
 
 	for {
-		var node yaml.Node
-		err := decoder.Decode(&node)
+		yamlEvent, err := parser.Next()
 		if err != nil {
-			if err == io.EOF {
-				break
+			// Report everything produced so far, then the error itself
+			if info := formatErrorInfo(err, offsets); info != nil {
+				if printErr := printErrorInfo(w, info, compact, style); printErr != nil {
+					return printErr
+				}
 			}
-			return fmt.Errorf("failed to decode YAML: %v", err)
+			return fmt.Errorf("failed to parse YAML: %w", err)
 		}
-
-		// Add document separator for all documents except the first
-		if !firstDoc {
-			fmt.Println("---")
+		if yamlEvent == nil {
+			break
 		}
-		firstDoc = false
 
-		events := processNodeToEvents(&node, profuse)
+		info := formatEventInfo(newEvent(yamlEvent, offsets), profuse)
+		if err := printEventInfo(w, info, compact, style); err != nil {
+			return err
+		}
+	}
 
-		if compact {
-			// For compact mode, output each event as a flow style mapping in a sequence
-			for _, event := range events {
-				info := formatEventInfo(event, profuse)
+	return nil
+}
 
-				// Create a YAML node with flow style for the mapping
-				compactNode := &yaml.Node{
-					Kind:  yaml.MappingNode,
-					Style: yaml.FlowStyle,
-				}
+// ProcessSuiteEvents reads YAML from r and writes its events to w in the
+// tree notation of the yaml-test-suite's test.event files, one event per
+// line, so that the output can be compared with the suite directly
+func ProcessSuiteEvents(r io.Reader, w io.Writer) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return &kindError{kind: KindIO, err: fmt.Errorf("failed to read input: %v", err)}
+	}
+	offsets := NewOffsetMap(input)
 
-				// Add the Event field
-				compactNode.Content = append(compactNode.Content,
-					&yaml.Node{Kind: yaml.ScalarNode, Value: "Event"},
-					&yaml.Node{Kind: yaml.ScalarNode, Value: info.Event})
+	parser, err := yaml.NewEventParser(bytes.NewReader(input))
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %v", err)
+	}
+	defer parser.Close()
 
-				// Add other fields if they exist
-				if info.Value != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Value"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Value})
-				}
-				if info.Style != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Style"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Style})
-				}
-				if info.Tag != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Tag"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Tag})
-				}
-				if info.Anchor != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Anchor"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Anchor})
-				}
-				if info.Head != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Head"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Head})
-				}
-				if info.Line != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Line"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Line})
-				}
-				if info.Foot != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Foot"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Foot})
-				}
-				if info.Pos != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Pos"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Pos})
-				}
+	for {
+		yamlEvent, err := parser.Next()
+		if err != nil {
+			// The events written so far stand, as in the suite's
+			// error tests
+			return fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		if yamlEvent == nil {
+			break
+		}
 
-				var buf bytes.Buffer
-				enc := yaml.NewEncoder(&buf)
-				enc.SetIndent(2)
-				if err := enc.Encode([]*yaml.Node{compactNode}); err != nil {
-					enc.Close()
-					return fmt.Errorf("failed to marshal compact event info: %v", err)
-				}
-				enc.Close()
-				fmt.Print(buf.String())
-			}
-		} else {
-			// For non-compact mode, output each event as a separate mapping
-			for _, event := range events {
-				info := formatEventInfo(event, profuse)
-
-				var buf bytes.Buffer
-				enc := yaml.NewEncoder(&buf)
-				enc.SetIndent(2)
-				if err := enc.Encode([]*EventInfo{info}); err != nil {
-					enc.Close()
-					return fmt.Errorf("failed to marshal event info: %v", err)
-				}
-				enc.Close()
-				fmt.Print(buf.String())
-			}
+		if line := formatSuiteEvent(newEvent(yamlEvent, offsets), input); line != "" {
+			fmt.Fprintln(w, line)
 		}
 	}
 
 	return nil
 }
 
-// processNodeToEvents converts a node to a slice of events for compact output
-func processNodeToEvents(node *yaml.Node, profuse bool) []*Event {
-	var events []*Event
-
-	// Add document start event
-	events = append(events, &Event{
-		Type:        "DOCUMENT-START",
-		StartLine:   node.Line,
-		StartColumn: node.Column,
-		EndLine:     node.Line,
-		EndColumn:   node.Column,
-	})
-
-	// Process the node content
-	events = append(events, processNodeToEventsRecursive(node, profuse)...)
-
-	// Add document end event
-	events = append(events, &Event{
-		Type:        "DOCUMENT-END",
-		StartLine:   node.Line,
-		StartColumn: node.Column,
-		EndLine:     node.Line,
-		EndColumn:   node.Column,
-	})
-
-	return events
+// newEvent converts a parser event into an Event.
+// The parser reports 0-based columns; they are made 1-based here to match
+// the positions shown for nodes and tokens.
+func newEvent(e *yaml.Event, offsets *OffsetMap) *Event {
+	event := &Event{
+		Type:           EventType(e.Type),
+		Value:          e.Value,
+		Anchor:         e.Anchor,
+		Tag:            e.Tag,
+		Style:          e.Style,
+		Implicit:       e.Implicit,
+		QuotedImplicit: e.QuotedImplicit,
+		StartLine:      e.StartLine,
+		StartColumn:    e.StartCol + 1,
+		StartIndex:     e.StartIndex,
+		StartOffset:    offsets.ByteOffset(e.StartIndex),
+		EndLine:        e.EndLine,
+		EndColumn:      e.EndCol + 1,
+		EndIndex:       e.EndIndex,
+		EndOffset:      offsets.ByteOffset(e.EndIndex),
+		HeadComment:    e.HeadComment,
+		LineComment:    e.LineComment,
+		FootComment:    e.FootComment,
+	}
+	if e.VersionMajor != 0 || e.VersionMinor != 0 {
+		event.Version = fmt.Sprintf("%d.%d", e.VersionMajor, e.VersionMinor)
+	}
+	for _, directive := range e.TagDirectives {
+		event.TagDirectives = append(event.TagDirectives, directive.Handle+" "+directive.Prefix)
+	}
+	return event
 }
 
-// processNodeToEventsRecursive recursively converts a node to events
-func processNodeToEventsRecursive(node *yaml.Node, profuse bool) []*Event {
-	var events []*Event
+// printEventInfo writes a single event as a one-item YAML sequence
+func printEventInfo(w io.Writer, info *EventInfo, compact bool, style YAMLStyle) error {
+	var buf bytes.Buffer
+	enc := newYAMLEncoder(&buf, style)
 
-	switch node.Kind {
-	case yaml.DocumentNode:
-		for _, child := range node.Content {
-			events = append(events, processNodeToEventsRecursive(child, profuse)...)
+	if compact {
+		// For compact mode, output each event as a flow style mapping in a sequence
+		compactNode := &yaml.Node{
+			Kind:  yaml.MappingNode,
+			Style: yaml.FlowStyle,
 		}
-	case yaml.MappingNode:
-		events = append(events, &Event{
-			Type:        "MAPPING-START",
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-			Style:       formatStyle(node.Style),
-			HeadComment: node.HeadComment,
-			LineComment: node.LineComment,
-			FootComment: node.FootComment,
-		})
-		for i := 0; i < len(node.Content); i += 2 {
-			if i+1 < len(node.Content) {
-				// Key
-				keyEvents := processNodeToEventsRecursive(node.Content[i], profuse)
-				events = append(events, keyEvents...)
-				// Value
-				valueEvents := processNodeToEventsRecursive(node.Content[i+1], profuse)
-				events = append(events, valueEvents...)
-			}
+
+		// Add the Event field
+		compactNode.Content = append(compactNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "Event"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: info.Event})
+
+		// Add other fields if they exist
+		if info.Value != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Value"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Value})
+		}
+		if info.Style != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Style"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Style})
+		}
+		if info.Tag != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Tag"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Tag})
+		}
+		if info.Anchor != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Anchor"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Anchor})
 		}
-		events = append(events, &Event{
-			Type:        "MAPPING-END",
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-		})
-	case yaml.SequenceNode:
-		events = append(events, &Event{
-			Type:        "SEQUENCE-START",
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-			Style:       formatStyle(node.Style),
-			HeadComment: node.HeadComment,
-			LineComment: node.LineComment,
-			FootComment: node.FootComment,
-		})
-		for _, child := range node.Content {
-			childEvents := processNodeToEventsRecursive(child, profuse)
-			events = append(events, childEvents...)
+		if info.Implicit != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Implicit"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Implicit})
 		}
-		events = append(events, &Event{
-			Type:        "SEQUENCE-END",
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-		})
-	case yaml.ScalarNode:
-		// Calculate end position for scalars based on value length
-		endLine := node.Line
-		endColumn := node.Column
-		if node.Value != "" {
-			// For single-line values, add the length to the column
-			if !strings.Contains(node.Value, "\n") {
-				endColumn += len(node.Value)
-			} else {
-				// For multi-line values, we'd need more complex logic
-				// For now, just use the start position
-				endColumn = node.Column
+		if info.Version != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Version"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Version})
+		}
+		if len(info.Tags) > 0 {
+			tagsNode := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, tag := range info.Tags {
+				tagsNode.Content = append(tagsNode.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Value: tag})
 			}
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Tags"},
+				tagsNode)
 		}
-
-		// Filter out default YAML tags
-		tag := node.Tag
-		if tag == "!!str" || tag == "!!int" || tag == "!!float" || tag == "!!bool" {
-			tag = ""
+		if info.Head != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Head"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Head})
+		}
+		if info.Line != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Line"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Line})
+		}
+		if info.Foot != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Foot"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Foot})
+		}
+		if info.Pos != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Pos"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Pos})
+		}
+		if info.Offset != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Offset"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Offset})
+		}
+		if info.Index != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Index"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Index})
 		}
 
-		events = append(events, &Event{
-			Type:        "SCALAR",
-			Value:       node.Value,
-			Anchor:      node.Anchor,
-			Tag:         tag,
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     endLine,
-			EndColumn:   endColumn,
-			Style:       formatStyle(node.Style),
-			HeadComment: node.HeadComment,
-			LineComment: node.LineComment,
-			FootComment: node.FootComment,
-		})
-	case yaml.AliasNode:
-		// Generate ALIAS event for alias nodes
-		events = append(events, &Event{
-			Type:        "ALIAS",
-			Value:       node.Value,
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-			HeadComment: node.HeadComment,
-			LineComment: node.LineComment,
-			FootComment: node.FootComment,
-		})
+		if err := enc.Encode([]*yaml.Node{compactNode}); err != nil {
+			enc.Close()
+			return &kindError{kind: KindEncode, err: fmt.Errorf("failed to marshal compact event info: %v", err)}
+		}
+	} else {
+		// For non-compact mode, output each event as a separate mapping
+		if err := enc.Encode([]*EventInfo{info}); err != nil {
+			enc.Close()
+			return &kindError{kind: KindEncode, err: fmt.Errorf("failed to marshal event info: %v", err)}
+		}
 	}
+	enc.Close()
+	fmt.Fprint(w, buf.String())
 
-	return events
+	return nil
 }
 
 // formatEventInfo converts an Event to an EventInfo struct for YAML encoding
@@ -317,7 +290,7 @@ func formatEventInfo(event *Event, profuse bool) *EventInfo {
 	if event.Value != "" {
 		info.Value = event.Value
 	}
-	if event.Style != "" {
+	if event.Style != "" && event.Style != "Plain" && event.Style != "Block" {
 		info.Style = event.Style
 	}
 	if event.Tag != "" {
@@ -326,6 +299,9 @@ func formatEventInfo(event *Event, profuse bool) *EventInfo {
 	if event.Anchor != "" {
 		info.Anchor = event.Anchor
 	}
+	info.Implicit = formatImplicit(event, profuse)
+	info.Version = event.Version
+	info.Tags = event.TagDirectives
 	if event.HeadComment != "" {
 		info.Head = event.HeadComment
 	}
@@ -336,12 +312,120 @@ func formatEventInfo(event *Event, profuse bool) *EventInfo {
 		info.Foot = event.FootComment
 	}
 	if profuse {
-		if event.StartLine == event.EndLine && event.StartColumn == event.EndColumn {
-			info.Pos = fmt.Sprintf("%d;%d", event.StartLine, event.StartColumn)
-		} else {
-			info.Pos = fmt.Sprintf("%d;%d-%d;%d", event.StartLine, event.StartColumn, event.EndLine, event.EndColumn)
-		}
+		info.Pos = formatPos(event.StartLine, event.StartColumn, event.EndLine, event.EndColumn)
+		info.Offset = formatOffset(event.StartOffset, event.EndOffset)
+		info.Index = formatOffset(event.StartIndex, event.EndIndex)
 	}
 
 	return info
 }
+
+// formatImplicit renders the implicit flags of an event.
+// Document markers always report the flag go-yaml sets, which tells an
+// implicit document end from a `...` marker. The tag resolution flags of
+// nodes are only shown in profuse mode.
+func formatImplicit(event *Event, profuse bool) string {
+	switch event.Type {
+	case EventDocumentStart, EventDocumentEnd:
+		if event.Implicit {
+			return "true"
+		}
+	case EventScalar:
+		if !profuse {
+			return ""
+		}
+		if event.Implicit && event.QuotedImplicit {
+			return "plain,quoted"
+		} else if event.Implicit {
+			return "plain"
+		} else if event.QuotedImplicit {
+			return "quoted"
+		}
+	case EventSequenceStart, EventMappingStart:
+		if profuse && event.Implicit {
+			return "true"
+		}
+	}
+	return ""
+}
+
+// suiteStyles maps scalar styles to their yaml-test-suite indicators
+var suiteStyles = map[string]string{
+	"Plain":   ":",
+	"Single":  "'",
+	"Double":  `"`,
+	"Literal": "|",
+	"Folded":  ">",
+}
+
+// formatSuiteEvent renders an event of input in yaml-test-suite notation,
+// such as "+MAP {} &a <tag:yaml.org,2002:map>" or "=VAL :foo". Comments
+// have no notation and give an empty string.
+func formatSuiteEvent(event *Event, input []byte) string {
+	switch event.Type {
+	case EventStreamStart:
+		return "+STR"
+	case EventStreamEnd:
+		return "-STR"
+	case EventDocumentStart:
+		// go-yaml does not flag implicit document starts, but only an
+		// explicit one starts with directives or a "---" marker
+		rest := input[event.StartOffset:]
+		if bytes.HasPrefix(rest, []byte("---")) || bytes.HasPrefix(rest, []byte("%")) {
+			return "+DOC ---"
+		}
+		return "+DOC"
+	case EventDocumentEnd:
+		if event.Implicit {
+			return "-DOC"
+		}
+		return "-DOC ..."
+	case EventMappingStart:
+		if event.Style == "Flow" {
+			return "+MAP {}" + suiteProperties(event)
+		}
+		return "+MAP" + suiteProperties(event)
+	case EventMappingEnd:
+		return "-MAP"
+	case EventSequenceStart:
+		if event.Style == "Flow" {
+			return "+SEQ []" + suiteProperties(event)
+		}
+		return "+SEQ" + suiteProperties(event)
+	case EventSequenceEnd:
+		return "-SEQ"
+	case EventAlias:
+		return "=ALI *" + event.Anchor
+	case EventScalar:
+		return "=VAL" + suiteProperties(event) + " " + suiteStyles[event.Style] + suiteEscape(event.Value)
+	}
+	return ""
+}
+
+// suiteProperties renders the anchor and tag of a node event
+func suiteProperties(event *Event) string {
+	var props string
+	if event.Anchor != "" {
+		props += " &" + event.Anchor
+	}
+	if event.Tag != "" {
+		props += " <" + event.Tag + ">"
+	}
+	return props
+}
+
+// suiteEscaper escapes scalar values the way the yaml-test-suite does, so
+// that every event fits on one line
+var suiteEscaper = strings.NewReplacer(
+	"\\", `\\`,
+	"\x00", `\0`,
+	"\b", `\b`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+// suiteEscape escapes a scalar value for the yaml-test-suite notation
+func suiteEscape(value string) string {
+	return suiteEscaper.Replace(value)
+}
@@ -87,3 +87,167 @@ settings:
 		}
 	}
 }
+
+// TestEventModeParserEvents tests that events come from the real parser
+func TestEventModeParserEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"stream markers",
+			"key: value",
+			[]string{"{Event: STREAM-START}", "{Event: STREAM-END}"},
+		},
+		{
+			"implicit document end",
+			"key: value",
+			[]string{"{Event: DOCUMENT-END, Implicit: true}"},
+		},
+		{
+			"explicit document",
+			"--- key\n...\n",
+			[]string{"{Event: DOCUMENT-START}", "{Event: DOCUMENT-END}"},
+		},
+		{
+			"alias",
+			"a: &x 1\nb: *x",
+			[]string{"{Event: SCALAR, Value: 1, Anchor: x}", "{Event: ALIAS, Anchor: x}"},
+		},
+		{
+			"flow collections",
+			"{a: [1]}",
+			[]string{"{Event: MAPPING-START, Style: Flow}", "{Event: SEQUENCE-START, Style: Flow}"},
+		},
+		{
+			"explicit tag",
+			"!!str 1",
+			[]string{"{Event: SCALAR, Value: 1, Tag: 'tag:yaml.org,2002:str'}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCommand(tt.input, "-e")
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if stderr != "" {
+				t.Errorf("Expected no stderr, got %q", stderr)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(stdout, expected) {
+					t.Errorf("Expected output to contain %q, got %q", expected, stdout)
+				}
+			}
+		})
+	}
+}
+
+// TestEventProfusePositions tests that -E reports real start and end marks
+func TestEventProfusePositions(t *testing.T) {
+	stdout, _, err := runCommand("a: [1, 2]\n", "-E")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := []string{
+		"{Event: SEQUENCE-START, Style: Flow, Implicit: true, Pos: 1;4-1;5, ",
+		"{Event: SEQUENCE-END, Pos: 1;9-1;10, ",
+		"{Event: SCALAR, Value: 1, Implicit: plain, Pos: 1;5-1;6, ",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+}
+
+// TestEventModeDirectives tests that document start events show their
+// directives
+func TestEventModeDirectives(t *testing.T) {
+	input := "%YAML 1.1\n%TAG !e! tag:example.com,2000:\n--- !e!foo x\n"
+
+	stdout, _, err := runCommand(input, "-e")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := []string{
+		"{Event: DOCUMENT-START, Version: 1.1, Tags: ['!e! tag:example.com,2000:']}",
+		"{Event: SCALAR, Value: x, Tag: 'tag:example.com,2000:foo'}",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+}
+
+// TestSuiteEvents tests the yaml-test-suite event notation
+func TestSuiteEvents(t *testing.T) {
+	input := "a\n--- !!map\n&x k: [1, \"y\\tz\"]\n? b\n: *x\nl: |\n  text\n...\n"
+	expected := `+STR
++DOC
+=VAL :a
+-DOC
++DOC ---
++MAP <tag:yaml.org,2002:map>
+=VAL &x :k
++SEQ []
+=VAL :1
+=VAL "y\tz
+-SEQ
+=VAL :b
+=ALI *x
+=VAL :l
+=VAL |text\n
+-MAP
+-DOC ...
+-STR
+`
+
+	for _, args := range [][]string{{"events", "--suite"}, {"--suite"}} {
+		stdout, stderr, err := runCommand(input, args...)
+		if err != nil {
+			t.Errorf("%v: Expected no error, got %v (%s)", args, err, stderr)
+		}
+		if stdout != expected {
+			t.Errorf("%v: Expected %q, got %q", args, expected, stdout)
+		}
+	}
+
+	// The events before a parse error are still written
+	stdout, _, err := runCommand("a: [1\n", "events", "--suite")
+	if code := exitCode(err); code != 5 {
+		t.Errorf("Expected exit code 5, got %d", code)
+	}
+	if !strings.HasSuffix(stdout, "+SEQ []\n=VAL :1\n") {
+		t.Errorf("Expected events up to the error, got %q", stdout)
+	}
+}
+
+// TestEventImplicitDocumentStart tests that events report the implicit flag
+// of a document start as go-yaml sets it, while the suite notation tells an
+// implicit start apart from an explicit "---"
+func TestEventImplicitDocumentStart(t *testing.T) {
+	input := "a: 1\n---\nb: 2\n"
+	stdout, stderr, err := runCommand(input, "-e")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v: %s", err, stderr)
+	}
+	lines := strings.Split(stdout, "\n")
+	for _, i := range []int{1, 7} {
+		if lines[i] != "- {Event: DOCUMENT-START}" {
+			t.Errorf("Expected a document start at line %d, got %q", i, lines[i])
+		}
+	}
+
+	stdout, stderr, err = runCommand(input, "--suite")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v: %s", err, stderr)
+	}
+	lines = strings.Split(stdout, "\n")
+	if lines[1] != "+DOC" || lines[7] != "+DOC ---" {
+		t.Errorf("Expected an implicit then an explicit document start, got %q", stdout)
+	}
+}
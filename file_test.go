@@ -90,6 +90,8 @@ func parseFlagsFromFilename(flagPart string) []string {
 		switch part {
 		case "n":
 			flags = append(flags, "-n")
+		case "N":
+			flags = append(flags, "-N")
 		case "e":
 			flags = append(flags, "-e")
 		case "t":
@@ -130,6 +132,8 @@ func mapLegacyFlags(flagPart string) []string {
 		return []string{"-T"}
 	case "e-p":
 		return []string{"-E"}
+	case "n-p":
+		return []string{"-N"}
 	case "e-p-l":
 		return []string{"-E", "-l"}
 	case "t-p-l":
@@ -0,0 +1,233 @@
+// Package main provides JSON to YAML conversion utilities for the go-yaml tool.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// FromJSONOptions controls the YAML written for JSON input
+type FromJSONOptions struct {
+	// Flow writes mappings and sequences in flow style instead of block
+	// style
+	Flow bool
+	// Quote is the quoting of string values: "auto" (the default) quotes
+	// only when needed, "single" and "double" always quote
+	Quote string
+	// Style is the layout of the YAML written
+	Style YAMLStyle
+}
+
+// jsonQuoteStyles maps the quoting options to scalar styles
+var jsonQuoteStyles = map[string]yaml.Style{
+	"":       0,
+	"auto":   0,
+	"single": yaml.SingleQuotedStyle,
+	"double": yaml.DoubleQuotedStyle,
+}
+
+// checkFromJSONOptions validates the quoting option of opts
+func checkFromJSONOptions(opts FromJSONOptions) error {
+	if _, ok := jsonQuoteStyles[opts.Quote]; !ok {
+		return fmt.Errorf("unknown quoting %q (use auto, single or double)", opts.Quote)
+	}
+	return nil
+}
+
+// ProcessFromJSON reads a stream of JSON values from r and writes each one to
+// w as a YAML document. Key order and the text of numbers are kept as they
+// are in the input.
+func ProcessFromJSON(r io.Reader, w io.Writer, opts FromJSONOptions) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return &kindError{kind: KindIO, err: fmt.Errorf("failed to read input: %v", err)}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	builder := &jsonNodeBuilder{
+		decoder: decoder,
+		input:   input,
+		flow:    opts.Flow,
+		quote:   jsonQuoteStyles[opts.Quote],
+	}
+
+	for docIndex := 0; ; docIndex++ {
+		node, err := builder.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read JSON: %w", err)
+		}
+
+		// Add document separator for all documents except the first
+		if docIndex > 0 {
+			fmt.Fprintln(w, "---")
+		}
+
+		doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}}
+		if err := encodeYAML(w, doc, opts.Style); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jsonNodeBuilder builds YAML nodes from the tokens of a JSON decoder
+type jsonNodeBuilder struct {
+	decoder *json.Decoder
+	input   []byte
+	flow    bool
+	quote   yaml.Style
+}
+
+// next returns the node for the next JSON value in the stream, or io.EOF
+func (b *jsonNodeBuilder) next() (*yaml.Node, error) {
+	token, err := b.token()
+	if err != nil {
+		return nil, err
+	}
+	return b.value(token, pathRoot)
+}
+
+// token reads the next token, reporting syntax errors with their position
+func (b *jsonNodeBuilder) token() (json.Token, error) {
+	offset := b.decoder.InputOffset()
+	token, err := b.decoder.Token()
+	if err == nil || errors.Is(err, io.EOF) {
+		return token, err
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	} else if errors.Is(err, io.ErrUnexpectedEOF) {
+		offset = int64(len(b.input))
+	}
+	line, column := b.position(offset)
+	return nil, &conversionError{kind: KindParse, line: line, column: column, message: err.Error()}
+}
+
+// position converts a byte offset of the input into a 1-based line and
+// column
+func (b *jsonNodeBuilder) position(offset int64) (int, int) {
+	return offsetPosition(b.input, int(offset))
+}
+
+// offsetPosition converts a byte offset of input into a 1-based line and
+// column, counting columns in characters
+func offsetPosition(input []byte, offset int) (int, int) {
+	if offset > len(input) {
+		offset = len(input)
+	}
+	before := string(input[:offset])
+	line := strings.Count(before, "\n") + 1
+	column := len([]rune(before[strings.LastIndex(before, "\n")+1:])) + 1
+	return line, column
+}
+
+// value builds the node for a JSON value starting with token
+func (b *jsonNodeBuilder) value(token json.Token, path string) (*yaml.Node, error) {
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '{' {
+			return b.mapping(path)
+		}
+		return b.sequence(path)
+	case string:
+		style := b.quote
+		if style == 0 && needsQuotes(t) {
+			style = yaml.DoubleQuotedStyle
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t, Style: style}, nil
+	case json.Number:
+		// The tag is left for the encoder to resolve, so that integers
+		// too large for go-yaml are not written with an explicit !!int
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: string(t)}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(t)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+}
+
+// collection returns an empty mapping or sequence node in the chosen style
+func (b *jsonNodeBuilder) collection(kind yaml.Kind, tag string) *yaml.Node {
+	node := &yaml.Node{Kind: kind, Tag: tag}
+	if b.flow {
+		node.Style = yaml.FlowStyle
+	}
+	return node
+}
+
+// mapping builds a mapping node from the members of a JSON object
+func (b *jsonNodeBuilder) mapping(path string) (*yaml.Node, error) {
+	node := b.collection(yaml.MappingNode, "!!map")
+	seen := make(map[string]bool)
+	for b.decoder.More() {
+		offset := b.decoder.InputOffset()
+		token, err := b.token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+		if needsQuotes(key) {
+			keyNode.Style = yaml.DoubleQuotedStyle
+		}
+		keyPath := pathKey(path, keyNode)
+		if seen[key] {
+			// Point at the key rather than the comma before it
+			for offset < int64(len(b.input)) && strings.IndexByte(", \t\r\n", b.input[offset]) >= 0 {
+				offset++
+			}
+			line, column := b.position(offset)
+			message := fmt.Sprintf("duplicate key %q", key)
+			return nil, &conversionError{kind: KindParse, path: keyPath, line: line, column: column, message: message}
+		}
+		seen[key] = true
+
+		token, err = b.token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := b.value(token, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, keyNode, value)
+	}
+	// Consume the closing brace
+	if _, err := b.token(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// sequence builds a sequence node from the items of a JSON array
+func (b *jsonNodeBuilder) sequence(path string) (*yaml.Node, error) {
+	node := b.collection(yaml.SequenceNode, "!!seq")
+	for i := 0; b.decoder.More(); i++ {
+		token, err := b.token()
+		if err != nil {
+			return nil, err
+		}
+		item, err := b.value(token, pathIndex(path, i))
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, item)
+	}
+	// Consume the closing bracket
+	if _, err := b.token(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
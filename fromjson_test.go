@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFromJSON tests that JSON input keeps key order and number text
+func TestFromJSON(t *testing.T) {
+	input := `{"z": 1.50, "a": [1e3, "true", null, false], "big": 12345678901234567890123, "e": {}}` + "\n[1]\n"
+	expected := "z: 1.50\na:\n  - 1e3\n  - \"true\"\n  - null\n  - false\nbig: 12345678901234567890123\ne: {}\n---\n- 1\n"
+
+	for _, args := range [][]string{{"from-json"}, {"--from-json"}} {
+		stdout, stderr, err := runCommand(input, args...)
+		if err != nil {
+			t.Errorf("%v: Expected no error, got %v (%s)", args, err, stderr)
+		}
+		if stdout != expected {
+			t.Errorf("%v: Expected %q, got %q", args, expected, stdout)
+		}
+	}
+}
+
+// TestFromJSONQuotesAmbiguousStrings tests that strings YAML 1.1 reads as
+// other types are quoted, as -y quotes them
+func TestFromJSONQuotesAmbiguousStrings(t *testing.T) {
+	input := `{"a": "yes", "b": "on", "c": "y", "d": "07:32:00", "e": "plain", "on": 1}`
+	expected := "a: \"yes\"\nb: \"on\"\nc: \"y\"\nd: \"07:32:00\"\ne: plain\n\"on\": 1\n"
+
+	stdout, stderr, err := runCommand(input, "from-json")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v: %s", err, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout)
+	}
+}
+
+// TestFromJSONStyles tests the flow and quoting options
+func TestFromJSONStyles(t *testing.T) {
+	input := `{"b": {"c": "x"}, "a": [1, "y"]}`
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--flow"}, "{b: {c: x}, a: [1, \"y\"]}\n"},
+		{[]string{"--quote=single"}, "b:\n  c: 'x'\na:\n  - 1\n  - 'y'\n"},
+		{[]string{"--quote=double", "--flow"}, "{b: {c: \"x\"}, a: [1, \"y\"]}\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr, err := runCommand(input, append([]string{"from-json"}, tt.args...)...)
+		if err != nil {
+			t.Errorf("%v: Expected no error, got %v (%s)", tt.args, err, stderr)
+		}
+		if stdout != tt.expected {
+			t.Errorf("%v: Expected %q, got %q", tt.args, tt.expected, stdout)
+		}
+	}
+
+	_, stderr, err := runCommand(input, "from-json", "--quote=back")
+	if code := exitCode(err); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(stderr, `unknown quoting "back"`) {
+		t.Errorf("Expected usage error, got %q", stderr)
+	}
+}
+
+// TestFromJSONErrors tests that bad JSON is reported with its position
+func TestFromJSONErrors(t *testing.T) {
+	_, stderr, err := runCommand("{\"a\": 1,\n \"a\": 2}", "from-json")
+	if code := exitCode(err); code != 5 {
+		t.Errorf("Expected exit code 5, got %d", code)
+	}
+	if !strings.Contains(stderr, "<stdin>:2:2: parse error in document 0: $.a: duplicate key \"a\"") {
+		t.Errorf("Expected duplicate key error, got %q", stderr)
+	}
+
+	_, stderr, err = runCommand("{\"a\": [1,\n ]}", "from-json")
+	if code := exitCode(err); code != 5 {
+		t.Errorf("Expected exit code 5, got %d", code)
+	}
+	if !strings.Contains(stderr, "<stdin>:1:10: parse error") {
+		t.Errorf("Expected syntax error, got %q", stderr)
+	}
+}
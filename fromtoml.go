@@ -0,0 +1,680 @@
+// Package main provides TOML to YAML conversion utilities for the go-yaml tool.
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ProcessFromTOML reads a TOML document from r and writes it to w as YAML.
+// Keys keep the order in which the document defines them, and every TOML
+// value maps onto a YAML scalar or collection except local times and local
+// date-times, which YAML has no type for and are written as strings.
+func ProcessFromTOML(r io.Reader, w io.Writer, style YAMLStyle, warn func(*Failure)) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return &kindError{kind: KindIO, err: fmt.Errorf("failed to read input: %v", err)}
+	}
+
+	parser := &tomlParser{
+		input:       input,
+		warn:        warn,
+		tables:      make(map[*yaml.Node]*tomlTableInfo),
+		tableArrays: make(map[*yaml.Node]bool),
+	}
+	root, err := parser.parse()
+	if err != nil {
+		return fmt.Errorf("failed to read TOML: %w", err)
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+	return encodeYAML(w, doc, style)
+}
+
+// tomlParser builds YAML nodes from TOML text
+type tomlParser struct {
+	input []byte
+	pos   int
+	warn  func(*Failure)
+	// tables describes the mapping nodes built for TOML tables
+	tables map[*yaml.Node]*tomlTableInfo
+	// tableArrays marks the sequence nodes built for arrays of tables
+	tableArrays map[*yaml.Node]bool
+}
+
+// tomlTableInfo is what the parser knows about a table, which decides
+// whether a later header or dotted key may add to it
+type tomlTableInfo struct {
+	path string
+	keys map[string]*yaml.Node
+	// defined is set once a [table] header names the table
+	defined bool
+	// dotted is set for tables created by dotted keys
+	dotted bool
+	// inline is set for inline tables, which are complete as written
+	inline bool
+}
+
+// TOML scalars other than strings, matched against the text of a value
+var (
+	tomlInteger      = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	tomlPrefixedInt  = regexp.MustCompile(`^0(x[0-9A-Fa-f](_?[0-9A-Fa-f])*|o[0-7](_?[0-7])*|b[01](_?[01])*)$`)
+	tomlFloatNumber  = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+	tomlSpecialFloat = regexp.MustCompile(`^[+-]?(inf|nan)$`)
+	tomlDateTime     = regexp.MustCompile(`^([0-9]{4}-[0-9]{2}-[0-9]{2})(?:[Tt ]([0-9]{2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]+)?)([Zz]|[+-][0-9]{2}:[0-9]{2})?)?$`)
+	tomlLocalTime    = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?$`)
+	tomlTimeFollows  = regexp.MustCompile(`^ [0-9]{2}:`)
+)
+
+// parse reads the whole document and returns its root mapping
+func (p *tomlParser) parse() (*yaml.Node, error) {
+	if strings.HasPrefix(string(p.input), "\ufeff") {
+		p.pos = len("\ufeff")
+	}
+
+	root := p.newTable(pathRoot)
+	current := root
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.input) {
+			return root, nil
+		}
+
+		if p.peek() == '[' {
+			table, err := p.header(root)
+			if err != nil {
+				return nil, err
+			}
+			current = table
+		} else if err := p.keyValue(current); err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fail returns a parse error at a byte offset of the input
+func (p *tomlParser) fail(offset int, path, format string, args ...interface{}) error {
+	line, column := offsetPosition(p.input, offset)
+	return &conversionError{
+		kind:    KindParse,
+		path:    path,
+		line:    line,
+		column:  column,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+// lossy reports a lossy conversion of the value at an offset
+func (p *tomlParser) lossy(offset int, path, format string, args ...interface{}) {
+	if p.warn == nil {
+		return
+	}
+	line, column := offsetPosition(p.input, offset)
+	p.warn(&Failure{
+		Kind:    KindWarning,
+		Message: fmt.Sprintf(format, args...),
+		Path:    path,
+		Line:    line,
+		Column:  column,
+	})
+}
+
+// peek returns the byte at the current position, or 0 at the end
+func (p *tomlParser) peek() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// describe names the input at the current position for messages
+func (p *tomlParser) describe() string {
+	if p.pos >= len(p.input) {
+		return "end of input"
+	}
+	r, _ := utf8.DecodeRune(p.input[p.pos:])
+	if r == '\n' || r == '\r' {
+		return "end of line"
+	}
+	return strconv.QuoteRune(r)
+}
+
+// skipSpace skips spaces and tabs
+func (p *tomlParser) skipSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment up to the end of its line
+func (p *tomlParser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for p.pos < len(p.input) && p.input[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+// newline skips a line break, reporting whether there was one
+func (p *tomlParser) newline() bool {
+	switch {
+	case p.peek() == '\n':
+		p.pos++
+		return true
+	case strings.HasPrefix(string(p.input[p.pos:]), "\r\n"):
+		p.pos += 2
+		return true
+	}
+	return false
+}
+
+// skipBlank skips whitespace, line breaks and comments
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		if !p.newline() {
+			return
+		}
+	}
+}
+
+// endOfLine expects the rest of the line to hold at most a comment
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.pos < len(p.input) && !p.newline() {
+		return p.fail(p.pos, "", "expected the end of the line, found %s", p.describe())
+	}
+	return nil
+}
+
+// newTable returns an empty mapping node for the table at path
+func (p *tomlParser) newTable(path string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	p.tables[node] = &tomlTableInfo{path: path, keys: make(map[string]*yaml.Node)}
+	return node
+}
+
+// add adds a key and its value to a table
+func (p *tomlParser) add(table *yaml.Node, key string, value *yaml.Node) {
+	table.Content = append(table.Content, tomlStringNode(key), value)
+	p.tables[table].keys[key] = value
+}
+
+// keyPath returns the path of a key in a table
+func (p *tomlParser) keyPath(table *yaml.Node, key string) string {
+	return pathKey(p.tables[table].path, &yaml.Node{Kind: yaml.ScalarNode, Value: key})
+}
+
+// key reads a possibly dotted key, returning its parts and their offsets
+func (p *tomlParser) key() ([]string, []int, error) {
+	var keys []string
+	var offsets []int
+	for {
+		p.skipSpace()
+		offsets = append(offsets, p.pos)
+		switch p.peek() {
+		case '"':
+			key, err := p.basicString(false, "")
+			if err != nil {
+				return nil, nil, err
+			}
+			keys = append(keys, key)
+		case '\'':
+			key, err := p.literalString(false, "")
+			if err != nil {
+				return nil, nil, err
+			}
+			keys = append(keys, key)
+		default:
+			start := p.pos
+			for p.pos < len(p.input) && isBareKeyByte(p.input[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, nil, p.fail(p.pos, "", "expected a key, found %s", p.describe())
+			}
+			keys = append(keys, string(p.input[start:p.pos]))
+		}
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, offsets, nil
+		}
+		p.pos++
+	}
+}
+
+// isBareKeyByte reports whether c may appear in a bare key
+func isBareKeyByte(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// header reads a [table] or [[array of tables]] header and returns the
+// table that the following keys belong to
+func (p *tomlParser) header(root *yaml.Node) (*yaml.Node, error) {
+	p.pos++
+	array := p.peek() == '['
+	if array {
+		p.pos++
+	}
+	keys, offsets, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(string(p.input[p.pos:]), closing) {
+		return nil, p.fail(p.pos, "", "expected %s after the table name, found %s", closing, p.describe())
+	}
+	p.pos += len(closing)
+
+	// Intermediate tables are created as needed; an array of tables
+	// stands for its last table
+	table := root
+	for i, key := range keys[:len(keys)-1] {
+		child, ok := p.tables[table].keys[key]
+		switch {
+		case !ok:
+			child = p.newTable(p.keyPath(table, key))
+			p.add(table, key, child)
+		case p.tableArrays[child]:
+			child = child.Content[len(child.Content)-1]
+		case p.tables[child] == nil || p.tables[child].inline:
+			return nil, p.fail(offsets[i], p.keyPath(table, key), "key %q is already defined as a value", key)
+		}
+		table = child
+	}
+
+	last := keys[len(keys)-1]
+	path := p.keyPath(table, last)
+	existing, ok := p.tables[table].keys[last]
+	if array {
+		if !ok {
+			existing = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			p.tableArrays[existing] = true
+			p.add(table, last, existing)
+		} else if !p.tableArrays[existing] {
+			return nil, p.fail(offsets[len(keys)-1], path, "key %q is already defined and is not an array of tables", last)
+		}
+		item := p.newTable(pathIndex(path, len(existing.Content)))
+		p.tables[item].defined = true
+		existing.Content = append(existing.Content, item)
+		return item, nil
+	}
+
+	if !ok {
+		existing = p.newTable(path)
+		p.add(table, last, existing)
+	}
+	info := p.tables[existing]
+	if info == nil || info.defined || info.dotted || info.inline {
+		return nil, p.fail(offsets[len(keys)-1], path, "table %q is already defined", strings.Join(keys, "."))
+	}
+	info.defined = true
+	return existing, nil
+}
+
+// keyValue reads a key = value pair into table. Dotted keys create the
+// tables they name.
+func (p *tomlParser) keyValue(table *yaml.Node) error {
+	keys, offsets, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return p.fail(p.pos, "", "expected = after the key, found %s", p.describe())
+	}
+	p.pos++
+	p.skipSpace()
+
+	for i, key := range keys[:len(keys)-1] {
+		child, ok := p.tables[table].keys[key]
+		if !ok {
+			child = p.newTable(p.keyPath(table, key))
+			p.tables[child].dotted = true
+			p.add(table, key, child)
+		} else if info := p.tables[child]; info == nil || !info.dotted || info.inline {
+			return p.fail(offsets[i], p.keyPath(table, key), "key %q is already defined", key)
+		}
+		table = child
+	}
+
+	last := keys[len(keys)-1]
+	path := p.keyPath(table, last)
+	if _, ok := p.tables[table].keys[last]; ok {
+		return p.fail(offsets[len(keys)-1], path, "duplicate key %q", last)
+	}
+	value, err := p.value(path)
+	if err != nil {
+		return err
+	}
+	p.add(table, last, value)
+	return nil
+}
+
+// value reads a value
+func (p *tomlParser) value(path string) (*yaml.Node, error) {
+	switch p.peek() {
+	case '"':
+		s, err := p.basicString(true, path)
+		if err != nil {
+			return nil, err
+		}
+		return tomlStringNode(s), nil
+	case '\'':
+		s, err := p.literalString(true, path)
+		if err != nil {
+			return nil, err
+		}
+		return tomlStringNode(s), nil
+	case '[':
+		return p.array(path)
+	case '{':
+		return p.inlineTable(path)
+	}
+	return p.scalar(path)
+}
+
+// scalar reads a boolean, number or date-time
+func (p *tomlParser) scalar(path string) (*yaml.Node, error) {
+	start := p.pos
+	for p.pos < len(p.input) && isScalarByte(p.input[p.pos]) {
+		p.pos++
+		// A space may separate the date and the time of a date-time
+		if p.pos-start == len("2006-01-02") && tomlTimeFollows.Match(p.input[p.pos:]) {
+			p.pos++
+		}
+	}
+	text := string(p.input[start:p.pos])
+	if text == "" {
+		return nil, p.fail(start, path, "expected a value, found %s", p.describe())
+	}
+
+	scalar := func(tag, value string) (*yaml.Node, error) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}, nil
+	}
+	switch {
+	case text == "true" || text == "false":
+		return scalar("!!bool", text)
+	case tomlInteger.MatchString(text) || tomlPrefixedInt.MatchString(text):
+		i, err := strconv.ParseInt(strings.ReplaceAll(text, "_", ""), 0, 64)
+		if err != nil {
+			return nil, p.fail(start, path, "integer %s is out of range", text)
+		}
+		return scalar("!!int", strconv.FormatInt(i, 10))
+	case tomlSpecialFloat.MatchString(text):
+		switch strings.TrimPrefix(text, "+") {
+		case "inf":
+			return scalar("!!float", ".inf")
+		case "-inf":
+			return scalar("!!float", "-.inf")
+		}
+		return scalar("!!float", ".nan")
+	case tomlFloatNumber.MatchString(text):
+		return scalar("!!float", strings.ReplaceAll(text, "_", ""))
+	case tomlDateTime.MatchString(text):
+		return p.dateTime(text, start, path)
+	case tomlLocalTime.MatchString(text):
+		if _, err := time.Parse("15:04:05.999999999", text); err != nil {
+			return nil, p.fail(start, path, "invalid time %s", text)
+		}
+		p.lossy(start, path, "local time %s written as a string", text)
+		return tomlStringNode(text), nil
+	}
+	return nil, p.fail(start, path, "invalid value %q", text)
+}
+
+// tomlStringNode returns the node for a string, quoted where YAML 1.1 would
+// read it as another type
+func tomlStringNode(s string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	if needsQuotes(s) {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	return node
+}
+
+// isScalarByte reports whether c may appear in a boolean, number or
+// date-time
+func isScalarByte(c byte) bool {
+	return isBareKeyByte(c) || c == '+' || c == '.' || c == ':'
+}
+
+// dateTime returns the timestamp node for a TOML date or date-time. Offset
+// date-times keep their offset; local date-times are written in the form
+// that go-yaml reads without a time zone.
+func (p *tomlParser) dateTime(text string, start int, path string) (*yaml.Node, error) {
+	m := tomlDateTime.FindStringSubmatch(text)
+	date, clock, zone := m[1], m[2], strings.ToUpper(m[3])
+
+	value := date
+	layout := "2006-01-02"
+	switch {
+	case clock != "" && zone != "":
+		value = date + "T" + clock + zone
+		layout = time.RFC3339Nano
+	case clock != "":
+		value = date + " " + clock
+		layout = "2006-01-02 15:04:05.999999999"
+	}
+	if _, err := time.Parse(layout, value); err != nil {
+		return nil, p.fail(start, path, "invalid date-time %s", text)
+	}
+	if clock != "" && zone == "" {
+		// A YAML timestamp without a zone is read as UTC, which would give a
+		// local date-time an offset it does not have
+		p.lossy(start, path, "local date-time %s written as a string", text)
+		return tomlStringNode(text), nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: value}, nil
+}
+
+// array reads an array, which may span lines
+func (p *tomlParser) array(path string) (*yaml.Node, error) {
+	p.pos++
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.pos++
+			return node, nil
+		}
+		item, err := p.value(pathIndex(path, len(node.Content)))
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, item)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return node, nil
+		default:
+			return nil, p.fail(p.pos, path, "expected , or ] in array, found %s", p.describe())
+		}
+	}
+}
+
+// inlineTable reads an inline table, which must fit on one line
+func (p *tomlParser) inlineTable(path string) (*yaml.Node, error) {
+	p.pos++
+	table := p.newTable(path)
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		p.close(table)
+		return table, nil
+	}
+	for {
+		if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			p.close(table)
+			return table, nil
+		default:
+			return nil, p.fail(p.pos, path, "expected , or } in inline table, found %s", p.describe())
+		}
+	}
+}
+
+// close marks an inline table and the tables its dotted keys created as
+// complete
+func (p *tomlParser) close(table *yaml.Node) {
+	p.tables[table].inline = true
+	for i := 1; i < len(table.Content); i += 2 {
+		if _, ok := p.tables[table.Content[i]]; ok {
+			p.close(table.Content[i])
+		}
+	}
+}
+
+// basicString reads a "basic" string or, where allowed, a multi-line
+// basic string in triple double quotes
+func (p *tomlParser) basicString(multiline bool, path string) (string, error) {
+	if multiline && strings.HasPrefix(string(p.input[p.pos:]), `"""`) {
+		p.pos += 3
+		p.newline()
+		return p.stringBody('"', true, true, path)
+	}
+	p.pos++
+	return p.stringBody('"', false, true, path)
+}
+
+// literalString reads a 'literal' string or, where allowed, a multi-line
+// literal string in triple single quotes
+func (p *tomlParser) literalString(multiline bool, path string) (string, error) {
+	if multiline && strings.HasPrefix(string(p.input[p.pos:]), `'''`) {
+		p.pos += 3
+		p.newline()
+		return p.stringBody('\'', true, false, path)
+	}
+	p.pos++
+	return p.stringBody('\'', false, false, path)
+}
+
+// stringBody reads the rest of a string up to its closing quote. A
+// multi-line string may end with up to two quotes of its own before the
+// closing three.
+func (p *tomlParser) stringBody(quote byte, multiline, escapes bool, path string) (string, error) {
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.input) {
+			return "", p.fail(p.pos, path, "unterminated string")
+		}
+		c := p.input[p.pos]
+		switch {
+		case c == quote && !multiline:
+			p.pos++
+			return b.String(), nil
+		case c == quote:
+			n := 0
+			for p.pos+n < len(p.input) && p.input[p.pos+n] == quote && n < 5 {
+				n++
+			}
+			if n >= 3 {
+				b.WriteString(strings.Repeat(string(quote), n-3))
+				p.pos += n
+				return b.String(), nil
+			}
+			b.WriteString(strings.Repeat(string(quote), n))
+			p.pos += n
+		case c == '\\' && escapes:
+			if err := p.escape(&b, multiline, path); err != nil {
+				return "", err
+			}
+		case c == '\n' || c == '\r':
+			if !multiline {
+				return "", p.fail(p.pos, path, "unterminated string")
+			}
+			if !p.newline() {
+				return "", p.fail(p.pos, path, "bare carriage return in string")
+			}
+			b.WriteByte('\n')
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			return "", p.fail(p.pos, path, "control character %q in string", c)
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// escape reads an escape sequence of a basic string. In a multi-line
+// string a backslash at the end of a line removes the line break and the
+// whitespace that follows.
+func (p *tomlParser) escape(b *strings.Builder, multiline bool, path string) error {
+	start := p.pos
+	p.pos++
+	if multiline {
+		rest := p.pos
+		p.skipSpace()
+		if p.newline() {
+			for {
+				p.skipSpace()
+				if !p.newline() {
+					return nil
+				}
+			}
+		}
+		p.pos = rest
+	}
+
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.input) {
+			return p.fail(start, path, "invalid escape sequence")
+		}
+		code, err := strconv.ParseUint(string(p.input[p.pos:p.pos+size]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.fail(start, path, "invalid escape sequence %s", p.input[start:p.pos+size])
+		}
+		b.WriteRune(rune(code))
+		p.pos += size
+	default:
+		return p.fail(start, path, "invalid escape sequence")
+	}
+	return nil
+}
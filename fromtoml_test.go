@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFromTOML tests that TOML input is converted to YAML in key order
+func TestFromTOML(t *testing.T) {
+	input := `# comment
+title = "Example"
+hex = 0xff
+big = 1_000
+neg = -inf
+when = 1979-05-27 07:32:00
+text = """
+one \
+  two"""
+raw = '''it's'''
+site."google.com" = true
+
+[owner]
+name = "Tom"
+
+[[products]]
+name = "Hammer"
+
+[products.size]
+d = 2
+
+[[products]]
+name = "Nail"
+`
+	expected := `title: Example
+hex: 255
+big: 1000
+neg: -.inf
+when: "1979-05-27 07:32:00"
+text: one two
+raw: it's
+site:
+  google.com: true
+owner:
+  name: Tom
+products:
+  - name: Hammer
+    size:
+      d: 2
+  - name: Nail
+`
+
+	for _, args := range [][]string{{"from-toml"}, {"--from-toml"}} {
+		stdout, stderr, err := runCommand(input, args...)
+		if err != nil {
+			t.Errorf("%v: Expected no error, got %v (%s)", args, err, stderr)
+		}
+		if stdout != expected {
+			t.Errorf("%v: Expected %q, got %q", args, expected, stdout)
+		}
+	}
+}
+
+// TestFromTOMLQuotesAmbiguousStrings tests that strings and local times that
+// YAML 1.1 reads as other types are quoted
+func TestFromTOMLQuotesAmbiguousStrings(t *testing.T) {
+	input := "a = \"yes\"\nb = 'on'\nt = 07:32:00\ns = \"plain\"\n"
+	expected := "a: \"yes\"\nb: \"on\"\nt: \"07:32:00\"\ns: plain\n"
+
+	stdout, stderr, err := runCommand(input, "from-toml")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v: %s", err, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout)
+	}
+	if !strings.Contains(stderr, "local time 07:32:00 written as a string") {
+		t.Errorf("Expected local time warning, got %q", stderr)
+	}
+}
+
+// TestFromTOMLLocalDateTime tests that a local date-time is written as a
+// string, so that converting it back to TOML does not give it a UTC offset
+func TestFromTOMLLocalDateTime(t *testing.T) {
+	input := "a = 1979-05-27T07:32:00\nb = 1979-05-27T07:32:00-08:00\nc = 1979-05-27\n"
+
+	yamlOut, stderr, err := runCommand(input, "from-toml")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v: %s", err, stderr)
+	}
+	if !strings.Contains(stderr, "local date-time 1979-05-27T07:32:00 written as a string") {
+		t.Errorf("Expected local date-time warning, got %q", stderr)
+	}
+
+	expected := "a = \"1979-05-27T07:32:00\"\nb = 1979-05-27T07:32:00-08:00\nc = 1979-05-27\n"
+	stdout, stderr, err := runCommand(yamlOut, "toml")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v: %s", err, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout)
+	}
+}
+
+// TestFromTOMLErrors tests that invalid TOML is a parse error at its
+// position
+func TestFromTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"duplicate key", "a = 1\na = 2", `<stdin>:2:1: parse error in document 0: $.a: duplicate key "a"`},
+		{"table defined twice", "[a]\nb = 1\n[a]", `<stdin>:3:2: parse error in document 0: $.a: table "a" is already defined`},
+		{"inline table extended", "a = {b = 1}\n[a]", `$.a: table "a" is already defined`},
+		{"unterminated string", `a = "x`, `<stdin>:1:7: parse error in document 0: $.a: unterminated string`},
+		{"trailing text", "a = 1 b", "<stdin>:1:7: parse error in document 0: expected the end of the line, found 'b'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, err := runCommand(tt.input, "from-toml")
+			if code := exitCode(err); code != 5 {
+				t.Errorf("Expected exit code 5, got %d", code)
+			}
+			if !strings.Contains(stderr, tt.expected) {
+				t.Errorf("Expected %q in stderr, got %q", tt.expected, stderr)
+			}
+		})
+	}
+}
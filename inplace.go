@@ -0,0 +1,116 @@
+// Package main provides in-place file rewriting for the go-yaml tool.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// checkInPlace validates --in-place and --backup against the other options
+// and the files of a run
+func checkInPlace(inPlace bool, backup string, compare bool, files []string) error {
+	if !inPlace {
+		if backup != "" {
+			return fmt.Errorf("--backup needs --in-place")
+		}
+		return nil
+	}
+	if compare {
+		return fmt.Errorf("--in-place cannot be used with --check or --diff")
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("--in-place needs file arguments")
+	}
+	for _, file := range files {
+		if file == "-" {
+			return fmt.Errorf("--in-place cannot rewrite stdin")
+		}
+	}
+	return nil
+}
+
+// checkInPlaceViews rejects runs in which more than one view would rewrite
+// the files, since each would overwrite what the other wrote
+func checkInPlaceViews(views []view) error {
+	rewriting := 0
+	for _, v := range views {
+		if v.inPlace {
+			rewriting++
+		}
+	}
+	if rewriting > 1 {
+		return fmt.Errorf("--in-place can only rewrite the files with one YAML view")
+	}
+	return nil
+}
+
+// rewriteFile replaces the content of the named file, which was input, with
+// output. Nothing is written when they are equal. With a backup suffix the
+// input is first saved next to the file under that suffix.
+func rewriteFile(name string, input, output []byte, backup string) error {
+	if string(input) == string(output) {
+		return nil
+	}
+	// A file holding only comments has no documents, so its output is empty.
+	// Writing that would truncate the file.
+	if len(bytes.TrimSpace(output)) == 0 && len(bytes.TrimSpace(input)) > 0 {
+		return &kindError{kind: KindFormat, err: fmt.Errorf("re-emitting the file leaves it empty, so it is not rewritten")}
+	}
+
+	// Rewrite the target of a symbolic link rather than replacing the link
+	target, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	if backup != "" {
+		if err := writeFileAtomic(target+backup, input, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write backup: %v", err)
+		}
+	}
+	return writeFileAtomic(target, output, info.Mode().Perm())
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of name,
+// syncs it and renames it over name, so that name always holds either its
+// old or its new content in full
+func writeFileAtomic(name string, data []byte, mode fs.FileMode) error {
+	dir := filepath.Dir(name)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	// Sync the directory so that the rename itself survives a crash. Not
+	// every system can sync a directory, so failing to is not an error.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestInPlace tests that -i rewrites changed files, keeps their mode and
+// leaves files that fail to parse untouched
+func TestInPlace(t *testing.T) {
+	dir := writeInputFiles(t, map[string]string{
+		"a.yaml":   "b: 1\na:   [1,2]\n",
+		"bad.yaml": "a: [\n",
+	})
+	a := filepath.Join(dir, "a.yaml")
+	bad := filepath.Join(dir, "bad.yaml")
+	if err := os.Chmod(a, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := runCommand("", "-Y", "-i", a, bad)
+	if code := exitCode(err); code != 5 {
+		t.Errorf("Expected exit code 5, got %d", code)
+	}
+	if stdout != "" {
+		t.Errorf("Expected no output, got %q", stdout)
+	}
+
+	data, _ := os.ReadFile(a)
+	if string(data) != "b: 1\na: [1, 2]\n" {
+		t.Errorf("Expected a.yaml to be rewritten, got %q", data)
+	}
+	if info, err := os.Stat(a); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("Expected a.yaml to keep mode 0640, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if data, _ := os.ReadFile(bad); string(data) != "a: [\n" {
+		t.Errorf("Expected bad.yaml to be untouched, got %q", data)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected only the two input files, got %d entries", len(entries))
+	}
+}
+
+// TestInPlaceBackup tests that --backup saves the original of each file
+// that is rewritten
+func TestInPlaceBackup(t *testing.T) {
+	dir := writeInputFiles(t, map[string]string{
+		"a.yaml": "b: 1\na: 2\n",
+		"b.yaml": "a: 1\n",
+	})
+	a := filepath.Join(dir, "a.yaml")
+	b := filepath.Join(dir, "b.yaml")
+
+	if _, stderr, err := runCommand("", "yaml", "--in-place", "--backup=.orig", a, b); err != nil {
+		t.Fatalf("Expected no error, got %v: %s", err, stderr)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "a: 2\nb: 1\n" {
+		t.Errorf("Expected a.yaml to be rewritten, got %q", data)
+	}
+	if data, _ := os.ReadFile(a + ".orig"); string(data) != "b: 1\na: 2\n" {
+		t.Errorf("Expected backup of a.yaml, got %q", data)
+	}
+	if _, err := os.Stat(b + ".orig"); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup of unchanged b.yaml, got %v", err)
+	}
+}
+
+// TestInPlaceErrors tests the usage errors of -i and --backup
+func TestInPlaceErrors(t *testing.T) {
+	dir := writeInputFiles(t, map[string]string{"a.yaml": "a: 1\n"})
+	a := filepath.Join(dir, "a.yaml")
+
+	tests := []struct {
+		flags    []string
+		expected string
+	}{
+		{[]string{"-y", "-i"}, "--in-place needs file arguments"},
+		{[]string{"-j", "-i", a}, "needs -y, -Y or --normalize"},
+		{[]string{"yaml", "--backup=.bak", a}, "--backup needs --in-place"},
+		{[]string{"yaml", "-i", "--diff", a}, "--in-place cannot be used with --check or --diff"},
+		{[]string{"json", "-i", a}, "--in-place does not apply to json"},
+		{[]string{"-y", "-Y", "-i", a}, "--in-place can only rewrite the files with one YAML view"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.flags, " "), func(t *testing.T) {
+			_, stderr, err := runCommand("", tt.flags...)
+			if code := exitCode(err); code != 2 {
+				t.Errorf("Expected exit code 2, got %d", code)
+			}
+			if !strings.Contains(stderr, tt.expected) {
+				t.Errorf("Expected %q in stderr, got %q", tt.expected, stderr)
+			}
+		})
+	}
+}
+
+// TestInPlaceCommentOnly tests that a file holding only comments, which has
+// no documents to re-emit, is not truncated
+func TestInPlaceCommentOnly(t *testing.T) {
+	dir := writeInputFiles(t, map[string]string{"c.yaml": "# only a comment\n"})
+	c := filepath.Join(dir, "c.yaml")
+
+	_, stderr, err := runCommand("", "-Y", "-i", c)
+	if code := exitCode(err); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr, "format error: re-emitting the file leaves it empty") {
+		t.Errorf("Expected format error, got %q", stderr)
+	}
+	if data, _ := os.ReadFile(c); string(data) != "# only a comment\n" {
+		t.Errorf("Expected c.yaml to be untouched, got %q", data)
+	}
+}
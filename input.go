@@ -0,0 +1,61 @@
+// Package main provides input file handling for the go-yaml tool.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandFileArgs expands the glob patterns among the positional arguments
+// into file names. Arguments without glob characters are kept as they are,
+// so that a missing file is reported when it is opened.
+func expandFileArgs(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("bad glob pattern %q: %v", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", arg)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// readInput reads the named file, or stdin for "-"
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	input, err := os.ReadFile(name)
+	if err != nil {
+		// The caller already names the file
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return nil, fmt.Errorf("failed to open input: %v", err)
+	}
+	return input, nil
+}
+
+// printFileHeader writes the header that precedes each file's output when
+// several files are processed
+func printFileHeader(w io.Writer, name string, first bool) {
+	if !first {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "==> %s <==\n", name)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeInputFiles creates the named YAML files in a temporary directory and
+// returns the directory
+func writeInputFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// TestFileArgument tests that a single file argument is read instead of stdin
+// and prints no header
+func TestFileArgument(t *testing.T) {
+	dir := writeInputFiles(t, map[string]string{"a.yaml": "a: 1\n"})
+
+	stdout, stderr, err := runCommand("", "-j", filepath.Join(dir, "a.yaml"))
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no stderr, got %q", stderr)
+	}
+	if stdout != "{\"a\":1}\n" {
+		t.Errorf("Expected %q, got %q", "{\"a\":1}\n", stdout)
+	}
+}
+
+// TestFileGlobHeaders tests that globs expand and each file gets a header
+func TestFileGlobHeaders(t *testing.T) {
+	dir := writeInputFiles(t, map[string]string{
+		"a.yaml": "a: 1\n",
+		"b.yaml": "b: 2\n",
+		"c.txt":  "c: 3\n",
+	})
+	a := filepath.Join(dir, "a.yaml")
+	b := filepath.Join(dir, "b.yaml")
+
+	for _, flag := range []string{"-y", "-j", "-t", "-e", "-n"} {
+		stdout, _, err := runCommand("", flag, filepath.Join(dir, "*.yaml"))
+		if err != nil {
+			t.Errorf("%s: Expected no error, got %v", flag, err)
+		}
+		if !strings.HasPrefix(stdout, "==> "+a+" <==\n") {
+			t.Errorf("%s: Expected output to start with header for %s, got %q", flag, a, stdout)
+		}
+		if !strings.Contains(stdout, "\n\n==> "+b+" <==\n") {
+			t.Errorf("%s: Expected output to contain header for %s, got %q", flag, b, stdout)
+		}
+		if strings.Contains(stdout, "c.txt") {
+			t.Errorf("%s: Expected c.txt not to match, got %q", flag, stdout)
+		}
+	}
+}
+
+// TestFileErrorsNameFile tests that errors name the failing file and that
+// the other files are still processed
+func TestFileErrorsNameFile(t *testing.T) {
+	dir := writeInputFiles(t, map[string]string{
+		"bad.yaml":  "a: [\n",
+		"good.yaml": "b: 2\n",
+	})
+	bad := filepath.Join(dir, "bad.yaml")
+	missing := filepath.Join(dir, "missing.yaml")
+
+	stdout, stderr, err := runCommand("", "-j", bad, missing, filepath.Join(dir, "good.yaml"))
+	if err == nil {
+		t.Errorf("Expected an error, got none")
+	}
+	if !strings.Contains(stderr, "Error: "+bad+":2:1: parse error") {
+		t.Errorf("Expected stderr to name %s, got %q", bad, stderr)
+	}
+	if !strings.Contains(stderr, "Error: "+missing+": io error: failed to open input") {
+		t.Errorf("Expected stderr to name %s, got %q", missing, stderr)
+	}
+	if !strings.Contains(stdout, "{\"b\":2}\n") {
+		t.Errorf("Expected output for good.yaml, got %q", stdout)
+	}
+
+	_, stderr, err = runCommand("", "-j", filepath.Join(dir, "*.json"))
+	if err == nil {
+		t.Errorf("Expected an error, got none")
+	}
+	if !strings.Contains(stderr, "no files match") {
+		t.Errorf("Expected a no match error, got %q", stderr)
+	}
+}
@@ -2,37 +2,475 @@
 package main
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
 
 	"go.yaml.in/yaml/v3"
 )
 
-// ProcessJSON reads YAML from stdin and outputs JSON encoding
-func ProcessJSON(pretty bool) error {
-	decoder := yaml.NewDecoder(os.Stdin)
+// JSONOptions controls how YAML that JSON cannot represent is converted
+type JSONOptions struct {
+	Pretty bool
+	// Lines writes each document as one compact line (JSON Lines)
+	Lines bool
+	// Array wraps all documents of the stream in one array
+	Array bool
+	// Keys is the policy for non-string mapping keys, integers included:
+	// "error" (the default), "stringify" uses the key's text and "pairs"
+	// writes such mappings as arrays of [key, value] pairs
+	Keys string
+	// Floats is the policy for infinities and NaN: "error" (the default),
+	// "string" or "null"
+	Floats string
+	// Warn is called for every lossy conversion
+	Warn func(*Failure)
+}
+
+// jsonKeyPolicies and jsonFloatPolicies list the accepted policy names
+var (
+	jsonKeyPolicies   = []string{"error", "stringify", "pairs"}
+	jsonFloatPolicies = []string{"error", "string", "null"}
+)
+
+// checkJSONOptions validates the policy names of opts
+func checkJSONOptions(opts JSONOptions) error {
+	if opts.Lines && opts.Array {
+		return fmt.Errorf("--jsonl and --json-array cannot be used together")
+	}
+	if opts.Lines && opts.Pretty {
+		return fmt.Errorf("--jsonl writes compact lines and cannot be pretty printed")
+	}
+	if !validPolicy(opts.Keys, jsonKeyPolicies) {
+		return fmt.Errorf("unknown key policy %q (use %s)", opts.Keys, strings.Join(jsonKeyPolicies, ", "))
+	}
+	if !validPolicy(opts.Floats, jsonFloatPolicies) {
+		return fmt.Errorf("unknown float policy %q (use %s)", opts.Floats, strings.Join(jsonFloatPolicies, ", "))
+	}
+	return nil
+}
+
+// validPolicy reports whether policy is empty or one of policies
+func validPolicy(policy string, policies []string) bool {
+	if policy == "" {
+		return true
+	}
+	for _, p := range policies {
+		if policy == p {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessJSON reads YAML from r and writes its JSON encoding to w.
+// The JSON is produced from the node tree, so mappings keep the key order of
+// the document. Each document is written on its own, unless opts.Array
+// collects them into one array.
+func ProcessJSON(r io.Reader, w io.Writer, opts JSONOptions) error {
+	decoder := yaml.NewDecoder(r)
+	documents := []interface{}{}
 
 	for {
 		// Read each document
-		var data interface{}
-		err := decoder.Decode(&data)
+		var node yaml.Node
+		err := decoder.Decode(&node)
 		if err != nil {
-			if err.Error() == "EOF" {
+			if errors.Is(err, io.EOF) {
 				break
 			}
-			return fmt.Errorf("failed to decode YAML: %v", err)
+			return fmt.Errorf("failed to decode YAML: %w", err)
+		}
+
+		converter := &jsonConverter{opts: opts}
+		data, err := converter.value(&node, pathRoot)
+		if err != nil {
+			return fmt.Errorf("failed to convert YAML: %w", err)
+		}
+
+		if opts.Array {
+			documents = append(documents, data)
+			continue
+		}
+		if err := encodeJSON(w, data, opts.Pretty); err != nil {
+			return err
+		}
+	}
+
+	if opts.Array {
+		return encodeJSON(w, documents, opts.Pretty)
+	}
+	return nil
+}
+
+// encodeJSON writes a value as JSON followed by a newline
+func encodeJSON(w io.Writer, data interface{}, pretty bool) error {
+	encoder := json.NewEncoder(w)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(data); err != nil {
+		return &kindError{kind: KindEncode, err: fmt.Errorf("failed to encode JSON: %v", err)}
+	}
+	return nil
+}
+
+// jsonObject is a JSON object that keeps its members in order
+type jsonObject []jsonMember
+
+// jsonMember is a single member of a jsonObject
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+// MarshalJSON writes the members in order
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(member.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonPair is a mapping pair on its way to JSON
+type jsonPair struct {
+	keyNode *yaml.Node
+	id      string
+	key     interface{}
+	value   interface{}
+	merged  bool
+}
+
+// maxAliasedNodes bounds how many nodes aliases may add to a document, so
+// that alias bombs fail instead of exhausting memory
+const maxAliasedNodes = 1 << 20
+
+// bigInteger matches plain integers that go-yaml resolves as floats because
+// they do not fit in 64 bits
+var bigInteger = regexp.MustCompile(`^[-+]?[0-9]+$`)
+
+// jsonConverter converts nodes into values for encoding/json, with
+// mappings as jsonObjects. Scalars are decoded the way go-yaml decodes them
+// into an interface{}, except where that would lose information. The
+// checks go-yaml makes when decoding into Go values are made here too.
+type jsonConverter struct {
+	opts JSONOptions
+	// aliases holds the alias targets being converted
+	aliases []*yaml.Node
+	aliased int
+}
+
+// warn reports a lossy conversion of the node at path
+func (c *jsonConverter) warn(path string, n *yaml.Node, format string, args ...interface{}) {
+	if c.opts.Warn == nil {
+		return
+	}
+	c.opts.Warn(&Failure{
+		Kind:    KindWarning,
+		Message: fmt.Sprintf(format, args...),
+		Path:    path,
+		Line:    n.Line,
+		Column:  n.Column,
+	})
+}
+
+// fail returns an error of the given kind for the node at path
+func (c *jsonConverter) fail(kind, path string, n *yaml.Node, format string, args ...interface{}) error {
+	return &conversionError{
+		kind:    kind,
+		path:    path,
+		line:    n.Line,
+		column:  n.Column,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+// value converts the node at path
+func (c *jsonConverter) value(n *yaml.Node, path string) (interface{}, error) {
+	if len(c.aliases) > 0 {
+		c.aliased++
+		if c.aliased > maxAliasedNodes {
+			return nil, c.fail(KindCompose, path, n, "document contains excessive aliasing")
+		}
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return c.value(n.Content[0], path)
+	case yaml.AliasNode:
+		return c.alias(n, path)
+	case yaml.SequenceNode:
+		items := make([]interface{}, 0, len(n.Content))
+		for i, child := range n.Content {
+			item, err := c.value(child, pathIndex(path, i))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case yaml.MappingNode:
+		return c.mapping(n, path)
+	}
+	return c.scalar(n, path)
+}
+
+// alias converts the target of an alias node at path
+func (c *jsonConverter) alias(n *yaml.Node, path string) (interface{}, error) {
+	for _, target := range c.aliases {
+		if target == n.Alias {
+			return nil, c.fail(KindCompose, path, n, "anchor '%s' value contains itself", n.Value)
+		}
+	}
+	c.aliases = append(c.aliases, n.Alias)
+	defer func() { c.aliases = c.aliases[:len(c.aliases)-1] }()
+
+	return c.value(n.Alias, path)
+}
+
+// scalar converts a scalar node at path
+func (c *jsonConverter) scalar(n *yaml.Node, path string) (interface{}, error) {
+	switch n.ShortTag() {
+	case "!!int":
+		// Integers are kept exact, however large; go-yaml would turn
+		// those beyond 64 bits into floats
+		if i, ok := new(big.Int).SetString(n.Value, 0); ok {
+			return json.Number(i.String()), nil
+		}
+	case "!!float":
+		if n.Tag == "!!float" && n.Style&yaml.TaggedStyle == 0 && bigInteger.MatchString(n.Value) {
+			if i, ok := new(big.Int).SetString(n.Value, 10); ok {
+				return json.Number(i.String()), nil
+			}
+		}
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, c.decodeError(path, n, err)
+		}
+		if !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return f, nil
 		}
+		switch c.opts.Floats {
+		case "string":
+			text := "NaN"
+			if math.IsInf(f, 1) {
+				text = "Infinity"
+			} else if math.IsInf(f, -1) {
+				text = "-Infinity"
+			}
+			c.warn(path, n, "%s written as the string %q", n.Value, text)
+			return text, nil
+		case "null":
+			c.warn(path, n, "%s written as null", n.Value)
+			return nil, nil
+		}
+		return nil, c.fail(KindEncode, path, n, "JSON cannot represent %s (see --floats)", n.Value)
+	case "!!binary":
+		// The base64 text is kept rather than the raw bytes, which need
+		// not be valid UTF-8
+		text := strings.Join(strings.Fields(n.Value), "")
+		if _, err := base64.StdEncoding.DecodeString(text); err == nil {
+			c.warn(path, n, "!!binary written as a base64 string")
+			return text, nil
+		}
+	case "!!timestamp":
+		// The text is kept as written rather than reformatted
+		c.warn(path, n, "!!timestamp written as a string")
+		return n.Value, nil
+	}
+
+	var value interface{}
+	if err := n.Decode(&value); err != nil {
+		return nil, c.decodeError(path, n, err)
+	}
+	return value, nil
+}
+
+// decodeError turns a go-yaml decoding error for the node at path into a
+// conversion error
+func (c *jsonConverter) decodeError(path string, n *yaml.Node, err error) error {
+	return c.fail(KindDecode, path, n, "%s", strings.TrimPrefix(err.Error(), "yaml: "))
+}
+
+// mapping converts a mapping node at path, applying the key policy when it
+// has keys that are not strings
+func (c *jsonConverter) mapping(n *yaml.Node, path string) (interface{}, error) {
+	var pairs []*jsonPair
+	if err := c.addPairs(&pairs, n, path, false); err != nil {
+		return nil, err
+	}
+
+	stringKeys := true
+	for _, pair := range pairs {
+		if _, ok := pair.key.(string); !ok {
+			stringKeys = false
+		}
+	}
+
+	if !stringKeys && c.opts.Keys == "pairs" {
+		c.warn(path, n, "mapping with non-string keys written as [key, value] pairs")
+		items := make([]interface{}, 0, len(pairs))
+		for _, pair := range pairs {
+			items = append(items, []interface{}{pair.key, pair.value})
+		}
+		return items, nil
+	}
+
+	object := make(jsonObject, 0, len(pairs))
+	for _, pair := range pairs {
+		key, err := c.key(pair, pathKey(path, pair.keyNode))
+		if err != nil {
+			return nil, err
+		}
+		object = append(object, jsonMember{key: key, value: pair.value})
+	}
+	return object, nil
+}
+
+// key returns the JSON member name of a pair
+func (c *jsonConverter) key(pair *jsonPair, path string) (string, error) {
+	keyNode := pair.keyNode
+	if keyNode.Kind == yaml.AliasNode {
+		keyNode = keyNode.Alias
+	}
 
-		// Encode as JSON
-		encoder := json.NewEncoder(os.Stdout)
-		if pretty {
-			encoder.SetIndent("", "  ")
+	switch key := pair.key.(type) {
+	case string:
+		return key, nil
+	case json.Number:
+		// Stringified integer keys are written as their decimal text, as
+		// encoding/json does for Go maps
+		if c.opts.Keys == "stringify" {
+			c.warn(path, keyNode, "integer key %s written as a string", keyNode.Value)
+			return string(key), nil
 		}
-		if err := encoder.Encode(data); err != nil {
-			return fmt.Errorf("failed to encode JSON: %v", err)
+	}
+
+	if c.opts.Keys != "stringify" {
+		if keyNode.Kind != yaml.ScalarNode {
+			return "", c.fail(KindEncode, path, keyNode, "%s key is not a string (see --keys)", keyNode.ShortTag())
 		}
+		return "", c.fail(KindEncode, path, keyNode, "%s key %q is not a string (see --keys)", keyNode.ShortTag(), keyNode.Value)
+	}
+	if keyNode.Kind == yaml.ScalarNode {
+		c.warn(path, keyNode, "%s key written as the string %q", keyNode.ShortTag(), keyNode.Value)
+		return keyNode.Value, nil
 	}
+	text, err := json.Marshal(pair.key)
+	if err != nil {
+		return "", c.fail(KindEncode, path, keyNode, "cannot write key as a string: %v", err)
+	}
+	c.warn(path, keyNode, "%s key written as the string %q", keyNode.ShortTag(), text)
+	return string(text), nil
+}
+
+// addPairs adds the pairs of a mapping at path. Explicit keys replace merged
+// ones wherever they appear, while merged keys never replace a key that is
+// already present, as with go-yaml's own merge handling.
+func (c *jsonConverter) addPairs(pairs *[]*jsonPair, n *yaml.Node, path string, merged bool) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keyNode, valueNode := n.Content[i], n.Content[i+1]
+
+		if keyNode.Kind == yaml.ScalarNode && keyNode.ShortTag() == "!!merge" {
+			if err := c.merge(pairs, valueNode, path); err != nil {
+				return err
+			}
+			continue
+		}
 
+		keyPath := pathKey(path, keyNode)
+		key, err := c.value(keyNode, keyPath+" (key)")
+		if err != nil {
+			return err
+		}
+		value, err := c.value(valueNode, keyPath)
+		if err != nil {
+			return err
+		}
+
+		pair := &jsonPair{keyNode: keyNode, id: pairID(keyNode), key: key, value: value, merged: merged}
+		if j := findPair(*pairs, pair.id); j >= 0 {
+			existing := (*pairs)[j]
+			if !merged && !existing.merged {
+				return c.fail(KindDecode, keyPath, keyNode, "mapping key %q already defined at line %d", keyNode.Value, existing.keyNode.Line)
+			}
+			if !merged {
+				existing.value = value
+				existing.merged = false
+			}
+			continue
+		}
+		*pairs = append(*pairs, pair)
+	}
 	return nil
 }
+
+// merge adds the pairs of the mappings named by a merge key
+func (c *jsonConverter) merge(pairs *[]*jsonPair, n *yaml.Node, path string) error {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		return c.addPairs(pairs, n, path, true)
+	case yaml.SequenceNode:
+		for _, child := range n.Content {
+			if err := c.merge(pairs, child, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return c.fail(KindDecode, path, n, "map merge requires map or sequence of maps as the value")
+}
+
+// pairID identifies scalar keys for merging; collection keys are never
+// equal to each other
+func pairID(keyNode *yaml.Node) string {
+	if keyNode.Kind == yaml.AliasNode {
+		keyNode = keyNode.Alias
+	}
+	if keyNode.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return keyNode.ShortTag() + " " + keyNode.Value
+}
+
+// findPair returns the index of the pair with the given id, or -1
+func findPair(pairs []*jsonPair, id string) int {
+	if id == "" {
+		return -1
+	}
+	for i, pair := range pairs {
+		if pair.id == id {
+			return i
+		}
+	}
+	return -1
+}
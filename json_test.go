@@ -35,7 +35,7 @@ func TestJSONMode(t *testing.T) {
 			"mixed types",
 			"data:\n  string: hello\n  number: 42\n  boolean: true\n  null: null",
 			[]string{},
-			true, // This should error because map[interface{}]interface{} can't be JSON encoded
+			true, // This should error because the null key is not a string
 		},
 	}
 
@@ -47,7 +47,7 @@ func TestJSONMode(t *testing.T) {
 				if err == nil {
 					t.Errorf("Expected error, got none")
 				}
-				if !strings.Contains(stderr, "failed to encode JSON") {
+				if !strings.Contains(stderr, "encode error") {
 					t.Errorf("Expected JSON encoding error, got %q", stderr)
 				}
 			} else {
@@ -149,7 +149,7 @@ func TestPrettyJSONMode(t *testing.T) {
 				if err == nil {
 					t.Errorf("Expected error, got none")
 				}
-				if !strings.Contains(stderr, "failed to encode JSON") {
+				if !strings.Contains(stderr, "encode error") {
 					t.Errorf("Expected JSON encoding error, got %q", stderr)
 				}
 			} else {
@@ -205,7 +205,7 @@ func TestCapitalJFlag(t *testing.T) {
 				if err == nil {
 					t.Errorf("Expected error, got none")
 				}
-				if !strings.Contains(stderr, "failed to encode JSON") {
+				if !strings.Contains(stderr, "encode error") {
 					t.Errorf("Expected JSON encoding error, got %q", stderr)
 				}
 			} else {
@@ -224,3 +224,181 @@ func TestCapitalJFlag(t *testing.T) {
 		})
 	}
 }
+
+// TestJSONKeepsKeyOrder tests that JSON output keeps the key order of the
+// document, including keys brought in by merges
+func TestJSONKeepsKeyOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"unsorted keys", "b: 1\na: 2\nc: {z: 1, y: 2}\n", "{\"b\":1,\"a\":2,\"c\":{\"z\":1,\"y\":2}}\n"},
+		{"aliases", "x: &x {d: 1, c: 2}\ny: *x\n", "{\"x\":{\"d\":1,\"c\":2},\"y\":{\"d\":1,\"c\":2}}\n"},
+		{"merge", "b: &b {x: 1, y: 2}\no:\n  y: 9\n  <<: *b\n  z: 3\n", "{\"b\":{\"x\":1,\"y\":2},\"o\":{\"y\":9,\"x\":1,\"z\":3}}\n"},
+		{"merge list", "- &a {k: 1}\n- &b {k: 2, m: 3}\n- <<: [*a, *b]\n", "[{\"k\":1},{\"k\":2,\"m\":3},{\"k\":1,\"m\":3}]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCommand(tt.input, "-j")
+			if err != nil {
+				t.Errorf("Expected no error, got %v (%s)", err, stderr)
+			}
+			if stdout != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, stdout)
+			}
+		})
+	}
+
+	stdout, _, err := runCommand("z: 1\na: [2]\n", "-J")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := "{\n  \"z\": 1,\n  \"a\": [\n    2\n  ]\n}\n"
+	if stdout != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout)
+	}
+}
+
+// TestJSONKeyPolicies tests the --keys policies for non-string keys
+func TestJSONKeyPolicies(t *testing.T) {
+	input := "a: 1\n~: 2\n[x]: 3\n"
+	tests := []struct {
+		policy   string
+		expected string
+		warnings []string
+	}{
+		{"stringify", "{\"a\":1,\"~\":2,\"[\\\"x\\\"]\":3}\n", []string{
+			"<stdin>:2:1 in document 0: $[\"~\"]: !!null key written as the string \"~\"",
+			"<stdin>:3:1 in document 0: $[[...]]: !!seq key written as the string \"[\\\"x\\\"]\"",
+		}},
+		{"pairs", "[[\"a\",1],[null,2],[[\"x\"],3]]\n", []string{
+			"<stdin>:1:1 in document 0: $: mapping with non-string keys written as [key, value] pairs",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			stdout, stderr, err := runCommand(input, "json", "--keys="+tt.policy)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if stdout != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, stdout)
+			}
+			for _, warning := range tt.warnings {
+				if !strings.Contains(stderr, "Warning: "+warning+"\n") {
+					t.Errorf("Expected warning %q, got %q", warning, stderr)
+				}
+			}
+		})
+	}
+
+	_, stderr, err := runCommand(input, "-j", "--error-format=json")
+	if code := exitCode(err); code != 8 {
+		t.Errorf("Expected exit code 8, got %d", code)
+	}
+	expected := `{"kind":"encode","message":"!!null key \"~\" is not a string (see --keys)","path":"$[\"~\"]","line":2,"column":1,"snippet":"~: 2","document":0}`
+	if !strings.Contains(stderr, expected) {
+		t.Errorf("Expected %q, got %q", expected, stderr)
+	}
+
+	// Integer keys are not strings either, so they need a policy too
+	_, stderr, err = runCommand("1: a\n", "json")
+	if code := exitCode(err); code != 8 {
+		t.Errorf("Expected exit code 8, got %d", code)
+	}
+	if !strings.Contains(stderr, `$["1"]: !!int key "1" is not a string (see --keys)`) {
+		t.Errorf("Expected integer key error, got %q", stderr)
+	}
+	stdout, stderr, err := runCommand("1: a\n0x10: b\n", "json", "--keys=stringify")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stdout != `{"1":"a","16":"b"}`+"\n" {
+		t.Errorf("Expected decimal integer keys, got %q", stdout)
+	}
+	if !strings.Contains(stderr, "integer key 0x10 written as a string") {
+		t.Errorf("Expected integer key warning, got %q", stderr)
+	}
+}
+
+// TestJSONLossyScalars tests special floats, big integers, binary and
+// timestamps
+func TestJSONLossyScalars(t *testing.T) {
+	input := "f: [.inf, -.inf, .nan]\nbig: 123456789012345678901234567890\nmax: 9007199254740993\nbin: !!binary aGk=\nts: 2001-12-14\n"
+
+	stdout, stderr, err := runCommand(input, "-j", "--floats=string")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := `{"f":["Infinity","-Infinity","NaN"],"big":123456789012345678901234567890,"max":9007199254740993,"bin":"aGk=","ts":"2001-12-14"}` + "\n"
+	if stdout != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout)
+	}
+	for _, warning := range []string{
+		"$.f[0]: .inf written as the string \"Infinity\"",
+		"$.bin: !!binary written as a base64 string",
+		"$.ts: !!timestamp written as a string",
+	} {
+		if !strings.Contains(stderr, warning) {
+			t.Errorf("Expected warning %q, got %q", warning, stderr)
+		}
+	}
+	if strings.Contains(stderr, "$.big") || strings.Contains(stderr, "$.max") {
+		t.Errorf("Expected no warnings for exact integers, got %q", stderr)
+	}
+
+	stdout, _, err = runCommand("f: .nan\n", "json", "--floats", "null")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stdout != "{\"f\":null}\n" {
+		t.Errorf("Expected null, got %q", stdout)
+	}
+
+	_, stderr, err = runCommand("f: .nan\n", "-j")
+	if code := exitCode(err); code != 8 {
+		t.Errorf("Expected exit code 8, got %d", code)
+	}
+	if !strings.Contains(stderr, "$.f: JSON cannot represent .nan (see --floats)") {
+		t.Errorf("Expected float error, got %q", stderr)
+	}
+}
+
+// TestJSONStreams tests --jsonl and --json-array with multi-document input
+func TestJSONStreams(t *testing.T) {
+	input := "a: 1\n---\nb: [2]\n"
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"jsonl", []string{"--jsonl"}, "{\"a\":1}\n{\"b\":[2]}\n"},
+		{"json-array", []string{"--json-array"}, "[{\"a\":1},{\"b\":[2]}]\n"},
+		{"pretty json-array", []string{"-J", "--json-array"}, "[\n  {\n    \"a\": 1\n  },\n  {\n    \"b\": [\n      2\n    ]\n  }\n]\n"},
+		{"subcommand jsonl", []string{"json", "--jsonl"}, "{\"a\":1}\n{\"b\":[2]}\n"},
+		{"subcommand json-array", []string{"json", "--json-array"}, "[{\"a\":1},{\"b\":[2]}]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCommand(input, tt.args...)
+			if err != nil {
+				t.Errorf("Expected no error, got %v (%s)", err, stderr)
+			}
+			if stdout != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, stdout)
+			}
+		})
+	}
+
+	_, stderr, err := runCommand(input, "json", "--jsonl", "--json-array")
+	if code := exitCode(err); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(stderr, "cannot be used together") {
+		t.Errorf("Expected usage error, got %q", stderr)
+	}
+}
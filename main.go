@@ -1,23 +1,30 @@
 // Package main provides a YAML node inspection tool that reads YAML from stdin
-// and outputs a detailed analysis of its node structure, including comments
-// and content organization.
+// or files and outputs a detailed analysis of its node structure, including
+// comments and content organization.
 package main
 
 import (
-	"bytes"
-	"errors"
 	"flag"
 	"fmt"
-	"go.yaml.in/yaml/v3"
-	"io"
-	"log"
 	"os"
 )
 
 const version = "3.0.3.1"
 
-// main reads YAML from stdin, parses it, and outputs the node structure
+// main reads YAML from stdin or files, parses it, and outputs the node structure
 func main() {
+	// The suite runner has its own options
+	if len(os.Args) > 1 && os.Args[1] == "suite" {
+		runSuite(os.Args[2:])
+		return
+	}
+
+	// A subcommand (or a comma separated list of them) comes first
+	if len(os.Args) > 1 && isCommand(os.Args[1]) {
+		runSubcommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	// Parse command line flags
 	showHelp := flag.Bool("h", false, "Show this help information")
 	showVersion := flag.Bool("version", false, "Show version information")
@@ -25,10 +32,27 @@ func main() {
 	// YAML modes
 	yamlMode := flag.Bool("y", false, "YAML encoding output")
 	yamlPreserveMode := flag.Bool("Y", false, "YAML style and comments preserved")
+	normalizeMode := flag.Bool("normalize", false, "YAML with key order and anchors kept, comments and styles dropped")
+	restyleRules := flag.String("restyle", "", "Style rules for -Y and --normalize: [path=]style,...")
+	checkMode := flag.Bool("check", false, "Fail when re-emitting the YAML would change it")
+	diffMode := flag.Bool("diff", false, "Show the changes re-emitting the YAML would make")
+	inPlaceMode := flag.Bool("i", false, "Rewrite the files with the YAML output")
+	backupSuffix := flag.String("backup", "", "Suffix of the backup of each file -i rewrites")
 
 	// JSON modes
 	jsonMode := flag.Bool("j", false, "JSON compact output")
 	jsonPrettyMode := flag.Bool("J", false, "JSON pretty output")
+	jsonLinesMode := flag.Bool("jsonl", false, "JSON Lines output")
+	jsonArrayMode := flag.Bool("json-array", false, "JSON array of all documents")
+
+	// JSON input mode
+	fromJSONMode := flag.Bool("from-json", false, "Convert JSON input to YAML")
+	flowMode := flag.Bool("flow", false, "Flow style collections for JSON input")
+	quoteStyle := flag.String("quote", "", "Quoting of strings for JSON input: auto, single or double")
+
+	// TOML modes
+	tomlMode := flag.Bool("toml", false, "TOML output")
+	fromTOMLMode := flag.Bool("from-toml", false, "Convert TOML input to YAML")
 
 	// Token modes
 	tokenMode := flag.Bool("t", false, "Token output")
@@ -37,12 +61,23 @@ func main() {
 	// Event modes
 	eventMode := flag.Bool("e", false, "Event output")
 	eventProfuseMode := flag.Bool("E", false, "Event with line info")
+	suiteMode := flag.Bool("suite", false, "Events in yaml-test-suite notation")
 
-	// Node mode
+	// Node modes
 	nodeMode := flag.Bool("n", false, "Node representation output")
+	nodeProfuseMode := flag.Bool("N", false, "Node with line info")
 
 	// Shared flags
 	longMode := flag.Bool("l", false, "Long (block) formatted output")
+	resolvedMode := flag.Bool("r", false, "Show resolved tags of all nodes")
+	expandMode := flag.Bool("x", false, "Expand alias targets inline")
+	annotateMode := flag.Bool("a", false, "Annotate source lines with spans")
+	errorFormat := flag.String("error-format", "text", "Error output format: json, yaml or text")
+	jsonKeys := flag.String("keys", "", "Non-string mapping keys in JSON: error, stringify or pairs")
+	jsonFloats := flag.String("floats", "", "Infinity and NaN in JSON: error, string or null")
+	indent := flag.String("indent", "", "Spaces per YAML indentation level: 2 to 9")
+	width := flag.String("width", "", "Preferred YAML line width (0: no limit)")
+	seqIndent := flag.String("seq-indent", "", "YAML sequence entries: indented or flush")
 
 	// Long flag aliases
 	flag.BoolVar(showHelp, "help", false, "Show this help information")
@@ -55,10 +90,21 @@ func main() {
 	flag.BoolVar(eventMode, "event", false, "Event output")
 	flag.BoolVar(eventProfuseMode, "EVENT", false, "Event with line info")
 	flag.BoolVar(nodeMode, "node", false, "Node representation output")
+	flag.BoolVar(nodeProfuseMode, "NODE", false, "Node with line info")
 	flag.BoolVar(longMode, "long", false, "Long (block) formatted output")
+	flag.BoolVar(resolvedMode, "resolved", false, "Show resolved tags of all nodes")
+	flag.BoolVar(expandMode, "expand", false, "Expand alias targets inline")
+	flag.BoolVar(annotateMode, "annotate", false, "Annotate source lines with spans")
+	flag.BoolVar(inPlaceMode, "in-place", false, "Rewrite the files with the YAML output")
 
 	flag.Parse()
 
+	rep, err := newReporter(*errorFormat)
+	if err != nil {
+		rep = &reporter{format: "text"}
+		rep.usage(err.Error())
+	}
+
 	// Show version and exit
 	if *showVersion {
 		fmt.Printf("go-yaml version %s\n", version)
@@ -71,104 +117,175 @@ func main() {
 		return
 	}
 
-	// Check if stdin has data
-	stat, err := os.Stdin.Stat()
+	// Positional arguments are files or glob patterns; without any, read stdin
+	files, err := expandFileArgs(flag.Args())
 	if err != nil {
-		log.Fatal("Failed to stat stdin:", err)
+		rep.usage(err.Error())
 	}
 
-	// If no stdin and no flags, show help
-	if (stat.Mode()&os.ModeCharDevice) != 0 && !*nodeMode && !*eventMode && !*eventProfuseMode && !*tokenMode && !*tokenProfuseMode && !*jsonMode && !*jsonPrettyMode && !*yamlMode && !*yamlPreserveMode && !*longMode {
-		printHelp()
-		return
-	}
-
-	// Error if stdin has data but no mode flags are provided
-	if (stat.Mode()&os.ModeCharDevice) == 0 && !*nodeMode && !*eventMode && !*eventProfuseMode && !*tokenMode && !*tokenProfuseMode && !*jsonMode && !*jsonPrettyMode && !*yamlMode && !*yamlPreserveMode && !*longMode {
-		fmt.Fprintf(os.Stderr, "Error: stdin has data but no mode specified. Use -n/--node, -e/--event, -E/--EVENT, -t/--token, -T/--TOKEN, -j/--json, -J/--JSON, -y/--yaml, -Y/--YAML flag.\n")
-		os.Exit(1)
-	}
+	modeSet := *nodeMode || *nodeProfuseMode || *eventMode || *eventProfuseMode || *suiteMode || *tokenMode || *tokenProfuseMode || *jsonMode || *jsonPrettyMode || *jsonLinesMode || *jsonArrayMode || *tomlMode || *yamlMode || *yamlPreserveMode || *normalizeMode || *checkMode || *diffMode || *inPlaceMode || *fromJSONMode || *fromTOMLMode || *longMode || *resolvedMode || *expandMode || *annotateMode
 
-	// Process YAML input
-	if *eventMode {
-		// Use event formatting mode (compact by default)
-		compact := !*longMode // compact is default, long mode negates it
-		if err := ProcessEvents(false, compact); err != nil {
-			log.Fatal("Failed to process events:", err)
-		}
-	} else if *eventProfuseMode {
-		// Use event formatting mode with profuse output
-		compact := !*longMode // compact is default, long mode negates it
-		if err := ProcessEvents(true, compact); err != nil {
-			log.Fatal("Failed to process events:", err)
+	if len(files) > 0 {
+		// Error if files are given but no mode flags are provided
+		if !modeSet {
+			rep.usage("files given but no mode specified. Use -n/--node, -N/--NODE, -e/--event, -E/--EVENT, -t/--token, -T/--TOKEN, -j/--json, -J/--JSON, -y/--yaml, -Y/--YAML flag.")
 		}
-	} else if *tokenMode {
-		// Use token formatting mode (compact by default)
-		compact := !*longMode // compact is default, long mode negates it
-		if err := ProcessTokens(false, compact); err != nil {
-			log.Fatal("Failed to process tokens:", err)
-		}
-	} else if *tokenProfuseMode {
-		// Use token formatting mode with profuse output
-		compact := !*longMode // compact is default, long mode negates it
-		if err := ProcessTokens(true, compact); err != nil {
-			log.Fatal("Failed to process tokens:", err)
+	} else {
+		// Check if stdin has data
+		stat, err := os.Stdin.Stat()
+		if err != nil {
+			rep.report(&Failure{Kind: KindIO, Message: fmt.Sprintf("failed to stat stdin: %v", err)})
+			rep.exit()
 		}
-	} else if *jsonMode {
-		// Use JSON formatting mode (compact by default)
-		if err := ProcessJSON(false); err != nil {
-			log.Fatal("Failed to process JSON:", err)
+
+		// If no stdin and no flags, show help
+		if (stat.Mode()&os.ModeCharDevice) != 0 && !modeSet {
+			printHelp()
+			return
 		}
-	} else if *jsonPrettyMode {
-		// Use pretty JSON formatting mode
-		if err := ProcessJSON(true); err != nil {
-			log.Fatal("Failed to process JSON:", err)
+
+		// Error if stdin has data but no mode flags are provided
+		if (stat.Mode()&os.ModeCharDevice) == 0 && !modeSet {
+			rep.usage("stdin has data but no mode specified. Use -n/--node, -N/--NODE, -e/--event, -E/--EVENT, -t/--token, -T/--TOKEN, -j/--json, -J/--JSON, -y/--yaml, -Y/--YAML flag.")
 		}
-	} else if *yamlMode {
-		// Use YAML formatting mode (clean by default)
-		if err := ProcessYAML(false); err != nil {
-			log.Fatal("Failed to process YAML:", err)
+	}
+
+	// Each mode flag adds a view; several views are shown in sections in
+	// pipeline order
+	opts := viewOptions{
+		long:      *longMode,
+		resolved:  *resolvedMode,
+		expand:    *expandMode,
+		annotate:  *annotateMode,
+		suite:     *suiteMode,
+		lines:     *jsonLinesMode,
+		array:     *jsonArrayMode,
+		keys:      *jsonKeys,
+		floats:    *jsonFloats,
+		flow:      *flowMode,
+		quote:     *quoteStyle,
+		normalize: *normalizeMode,
+		restyle:   *restyleRules,
+		check:     *checkMode,
+		diff:      *diffMode,
+		inPlace:   *inPlaceMode,
+		backup:    *backupSuffix,
+	}
+	if err := checkYAMLOptions(YAMLOptions{Preserve: *yamlPreserveMode, Normalize: *normalizeMode, Restyle: *restyleRules}); err != nil {
+		rep.usage(err.Error())
+	}
+	if (*checkMode || *diffMode) && !*yamlMode && !*yamlPreserveMode && !*normalizeMode {
+		rep.usage("--check and --diff compare YAML output and need -y, -Y or --normalize")
+	}
+	if *inPlaceMode && !*yamlMode && !*yamlPreserveMode && !*normalizeMode {
+		rep.usage("--in-place rewrites files with YAML output and needs -y, -Y or --normalize")
+	}
+	if err := checkInPlace(*inPlaceMode, *backupSuffix, *checkMode || *diffMode, files); err != nil {
+		rep.usage(err.Error())
+	}
+	if err := checkFromJSONOptions(FromJSONOptions{Quote: opts.quote}); err != nil {
+		rep.usage(err.Error())
+	}
+	opts.style, err = parseYAMLStyle(*indent, *width, *seqIndent)
+	if err != nil {
+		rep.usage(err.Error())
+	}
+	rep.style = opts.style
+	if err := checkJSONOptions(JSONOptions{Pretty: *jsonPrettyMode, Lines: opts.lines, Array: opts.array, Keys: opts.keys, Floats: opts.floats}); err != nil {
+		rep.usage(err.Error())
+	}
+	modes := []struct {
+		set   bool
+		label string
+		name  string
+		alt   bool
+	}{
+		{*tokenMode, "token", "tokens", false},
+		{*tokenProfuseMode, "TOKEN", "tokens", true},
+		// --suite implies -e when no event mode is given
+		{*eventMode || (*suiteMode && !*eventProfuseMode), "event", "events", false},
+		{*eventProfuseMode, "EVENT", "events", true},
+		{*nodeMode, "node", "nodes", false},
+		{*nodeProfuseMode, "NODE", "nodes", true},
+		// --jsonl and --json-array imply -j when no JSON mode is given
+		{*jsonMode || ((*jsonLinesMode || *jsonArrayMode) && !*jsonPrettyMode), "json", "json", false},
+		{*jsonPrettyMode, "JSON", "json", true},
+		{*tomlMode, "toml", "toml", false},
+		// --normalize implies -y
+		{*yamlMode || *normalizeMode, "yaml", "yaml", false},
+		{*yamlPreserveMode, "YAML", "yaml", true},
+		{*fromJSONMode, "from-json", "from-json", false},
+		{*fromTOMLMode, "from-toml", "from-toml", false},
+	}
+	var views []view
+	var names []string
+	for _, mode := range modes {
+		if !mode.set {
+			continue
 		}
-	} else if *yamlPreserveMode {
-		// Use YAML formatting mode with preserve
-		if err := ProcessYAML(true); err != nil {
-			log.Fatal("Failed to process YAML:", err)
+		names = append(names, mode.name)
+		// The upper case flags select the profuse, pretty or preserving
+		// variant of a view
+		modeOpts := opts
+		modeOpts.profuse = mode.alt
+		modeOpts.pretty = mode.alt
+		modeOpts.preserve = mode.alt
+		v, err := newView(mode.name, modeOpts)
+		if err != nil {
+			rep.usage(err.Error())
 		}
-	} else {
+		v.label = mode.label
+		views = append(views, v)
+	}
+	defaultView := len(views) == 0
+	if defaultView {
 		// Use node formatting mode (default)
-		reader := io.Reader(os.Stdin)
-		dec := yaml.NewDecoder(reader)
-		firstDoc := true
-
-		for {
-			var node yaml.Node
-			err := dec.Decode(&node)
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			if err != nil {
-				log.Fatal("Failed to load YAML node:", err)
-			}
-
-			// Add document separator for all documents except the first
-			if !firstDoc {
-				fmt.Println("---")
-			}
-			firstDoc = false
-
-			info := FormatNode(node)
-
-			// Use encoder with 2-space indentation
-			var buf bytes.Buffer
-			enc := yaml.NewEncoder(&buf)
-			enc.SetIndent(2)
-			if err := enc.Encode(info); err != nil {
-				log.Fatal("Failed to marshal node info:", err)
-			}
-			enc.Close()
-			fmt.Print(buf.String())
+		v, err := newView("nodes", opts)
+		if err != nil {
+			rep.usage(err.Error())
 		}
+		views = append(views, v)
+		names = append(names, "nodes")
+	}
+
+	// As with subcommands, options that none of the selected views uses are
+	// an error rather than being silently ignored. The mode flags that only
+	// select a view, and the options that imply one, always apply. -l alone
+	// has always shown nodes, which it does not change, so it is accepted
+	// with the default view.
+	flags := map[string]bool{
+		"long":     *longMode && !defaultView,
+		"resolved": *resolvedMode,
+		"expand":   *expandMode,
+		"annotate": *annotateMode,
+		"flow":     *flowMode,
+		"check":    *checkMode,
+		"diff":     *diffMode,
+		"in-place": *inPlaceMode,
+	}
+	values := map[string]string{
+		"keys":       *jsonKeys,
+		"floats":     *jsonFloats,
+		"quote":      *quoteStyle,
+		"restyle":    *restyleRules,
+		"backup":     *backupSuffix,
+		"indent":     *indent,
+		"width":      *width,
+		"seq-indent": *seqIndent,
+	}
+	if err := checkUnusedFlags(names, flags, values); err != nil {
+		rep.usage(err.Error())
+	}
+	if err := checkAnnotateOptions(*annotateMode, *suiteMode, *tokenProfuseMode || *eventProfuseMode, *longMode); err != nil {
+		rep.usage(err.Error())
 	}
+	if err := checkInPlaceViews(views); err != nil {
+		rep.usage(err.Error())
+	}
+
+	// Process YAML input
+	runViews(views, files, rep)
+	rep.exit()
 }
 
 // printHelp displays the help information for the program
@@ -179,24 +296,80 @@ A tool to show how go.yaml.in/yaml/v3 handles YAML both internally and
 externally.
 
 Usage:
+  go-yaml <command>[,<command>...] [command options] [file|glob...]
   go-yaml [options] < input.yaml
+  go-yaml [options] file|glob...
+  go-yaml suite [--baseline=<file>] <dir>
+
+Commands:
+  tokens           Token output (-p line info, -l long, -a annotated)
+  events           Event output (-p line info, -l long, -a annotated,
+                   --suite yaml-test-suite notation)
+  nodes            Node representation output (-p line info, -r, -x)
+  json             JSON output (--pretty, --jsonl, --json-array)
+  toml             TOML output
+  yaml             YAML output (--preserve, --normalize, --restyle=...,
+                   --check, --diff, -i, --backup=...)
+  from-json        Convert JSON input to YAML (--flow, --quote=...)
+  from-toml        Convert TOML input to YAML
+  suite            Run a yaml-test-suite checkout and show a pass/fail matrix
+
+A comma separated list of commands, or several mode options, shows each view
+of the same input in its own "=== view ===" section. With more than one file,
+the output for each file is preceded by a "==> file <==" header.
+
+Errors carry their kind, and each kind has its own exit code: format 1,
+usage 2, io 3, scan 4, parse 5, compose 6, decode 7 and encode 8.
 
 Options:
   -y, --yaml       YAML encoding output
   -Y, --YAML       YAML style and comments preserved
+  --normalize      YAML with key order and anchors kept, comments and
+                   scalar styles dropped
+  --restyle=...    Rewrite styles of -Y and --normalize output: a comma
+                   separated list of [path=]style, where style is double,
+                   single, plain, literal, flow or block
+  --check          Fail when re-emitting the YAML would change the input
+  --diff           Show the changes re-emitting would make as a unified diff
+  -i, --in-place   Rewrite the files with the YAML output
+  --backup=...     Save each file -i rewrites under this suffix first
 
   -j, --json       JSON compact output
   -J, --JSON       JSON pretty output
+  --jsonl          One compact JSON document per line
+  --json-array     Wrap all documents in one JSON array
+
+  --from-json      Convert JSON input to YAML
+  --flow           Flow style collections (JSON input)
+  --quote=...      Quoting of strings: auto (default), single or double
+                   (JSON input)
+
+  --toml           TOML output
+  --from-toml      Convert TOML input to YAML
 
   -t, --token      Token output
   -T, --TOKEN      Token with line info
 
   -e, --event      Event output
   -E, --EVENT      Event with line info
+  --suite          Events in yaml-test-suite notation
 
   -n, --node       Node representation output
+  -N, --NODE       Node with line info
 
   -l, --long       Long (block) formatted output
+  -r, --resolved   Show resolved tags of all nodes (node modes)
+  -x, --expand     Expand alias targets inline (node modes)
+  -a, --annotate   Annotate source lines with spans (token and event modes)
+
+  --keys=...       Non-string mapping keys in JSON: error (default),
+                   stringify or pairs
+  --floats=...     Infinity and NaN in JSON: error (default), string or null
+  --error-format   Error output format: text (default), json or yaml
+
+  --indent=...     Spaces per YAML indentation level: 2 (default) to 9
+  --width=...      Preferred YAML line width; 0 (default) never folds
+  --seq-indent=... YAML sequence entries: indented (default) or flush
 
   -h, --help       Show this help information
   --version        Show version information
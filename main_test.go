@@ -118,10 +118,13 @@ func TestLongFlags(t *testing.T) {
 		expected string
 	}{
 		{"--node flag", []string{"--node"}, "kind: Document"},
+		{"--NODE flag", []string{"--NODE"}, "pos: 1;1"},
 		{"--event flag", []string{"--event"}, "Event: DOCUMENT-START"},
 		{"--token flag", []string{"--token"}, "Token: STREAM-START"},
 		{"--json flag", []string{"--json"}, `"key":"value"`},
 		{"--yaml flag", []string{"--yaml"}, "key: value"},
+		// Alone, --long keeps showing nodes as it always has
+		{"--long flag", []string{"--long"}, "kind: Document"},
 	}
 
 	for _, tt := range tests {
@@ -2,24 +2,202 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
 	"go.yaml.in/yaml/v3"
 )
 
 // NodeInfo represents the information about a YAML node
 type NodeInfo struct {
-	Kind    string      `yaml:"kind"`
-	Style   string      `yaml:"style,omitempty"`
-	Anchor  string      `yaml:"anchor,omitempty"`
-	Tag     string      `yaml:"tag,omitempty"`
-	Head    string      `yaml:"head,omitempty"`
-	Line    string      `yaml:"line,omitempty"`
-	Foot    string      `yaml:"foot,omitempty"`
-	Text    string      `yaml:"text,omitempty"`
-	Content []*NodeInfo `yaml:"content,omitempty"`
+	Kind       string      `yaml:"kind"`
+	Directives []string    `yaml:"directives,omitempty"`
+	Style      string      `yaml:"style,omitempty"`
+	Anchor     string      `yaml:"anchor,omitempty"`
+	Tag        string      `yaml:"tag,omitempty"`
+	Shorthand  string      `yaml:"shorthand,omitempty"`
+	Tagging    string      `yaml:"tagging,omitempty"`
+	Head       string      `yaml:"head,omitempty"`
+	Line       string      `yaml:"line,omitempty"`
+	Foot       string      `yaml:"foot,omitempty"`
+	Text       string      `yaml:"text,omitempty"`
+	Pos        string      `yaml:"pos,omitempty"`
+	Target     *TargetInfo `yaml:"target,omitempty"`
+	Content    []*NodeInfo `yaml:"content,omitempty"`
+}
+
+// TargetInfo represents the anchored node an alias refers to
+type TargetInfo struct {
+	Anchor    string    `yaml:"anchor"`
+	Path      string    `yaml:"path,omitempty"`
+	Pos       string    `yaml:"pos"`
+	Cycle     bool      `yaml:"cycle,omitempty"`
+	Truncated bool      `yaml:"truncated,omitempty"`
+	Node      *NodeInfo `yaml:"node,omitempty"`
+}
+
+// maxExpandedNodes bounds how many nodes alias expansion may add to a
+// document, so that alias bombs do not hang the tool
+const maxExpandedNodes = 10000
+
+// ProcessNodes reads YAML from r and writes the node representation of each
+// document to w. With resolved set, every node shows its tag along with
+// whether it was explicit in the source or implicitly resolved. With expand
+// set, aliases also show a copy of the subtree they refer to.
+func ProcessNodes(r io.Reader, w io.Writer, profuse, resolved, expand bool, style YAMLStyle) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return &kindError{kind: KindIO, err: fmt.Errorf("failed to read input: %v", err)}
+	}
+
+	// Directive, tag and end position information is not kept on
+	// yaml.Node, so it is collected from the scanner and parser first. If
+	// that fails, the decoder below reports the error for the offending
+	// document.
+	sources, _ := collectNodeSources(input)
+	formatter := &nodeFormatter{
+		sources:  sources,
+		profuse:  profuse,
+		resolved: resolved,
+		expand:   expand,
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(input))
+	for docIndex := 0; ; docIndex++ {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load YAML node: %w", err)
+		}
+
+		// Add document separator for all documents except the first
+		if docIndex > 0 {
+			fmt.Fprintln(w, "---")
+		}
+
+		formatter.paths = nodePaths(&node)
+		formatter.expanded = 0
+		info := formatter.format(&node)
+		if docIndex < len(sources.directives) {
+			info.Directives = sources.directives[docIndex]
+		}
+
+		var buf bytes.Buffer
+		enc := newYAMLEncoder(&buf, style)
+		if err := enc.Encode(info); err != nil {
+			return &kindError{kind: KindEncode, err: fmt.Errorf("failed to marshal node info: %v", err)}
+		}
+		enc.Close()
+		fmt.Fprint(w, buf.String())
+	}
+
+	return nil
+}
+
+// nodeSources holds what the source text said about nodes, which the
+// composed yaml.Node tree no longer has
+type nodeSources struct {
+	// directives lists the %YAML and %TAG directives of each document
+	directives [][]string
+	// shorthands lists the tags of all explicitly tagged nodes as written
+	// in the source, in document order
+	shorthands []string
+	// scalarEnds lists the end line and column of all scalars, in
+	// document order
+	scalarEnds [][2]int
+}
+
+// collectNodeSources scans the input for directives, tag shorthands and
+// scalar end positions
+func collectNodeSources(input []byte) (*nodeSources, error) {
+	sources := &nodeSources{}
+
+	parser, err := yaml.NewParser(bytes.NewReader(input))
+	if err != nil {
+		return sources, err
+	}
+	defer parser.Close()
+	for {
+		token, err := parser.Next()
+		if err != nil {
+			return sources, err
+		}
+		if token == nil {
+			break
+		}
+		if token.Type != "TAG" {
+			continue
+		}
+		switch {
+		case token.Value == "" && token.Suffix == "!":
+			// The non-specific '!' tag does not mark a node as tagged
+		case token.Value == "":
+			sources.shorthands = append(sources.shorthands, "!<"+token.Suffix+">")
+		default:
+			sources.shorthands = append(sources.shorthands, token.Value+token.Suffix)
+		}
+	}
+
+	eventParser, err := yaml.NewEventParser(bytes.NewReader(input))
+	if err != nil {
+		return sources, err
+	}
+	defer eventParser.Close()
+	for {
+		event, err := eventParser.Next()
+		if err != nil {
+			return sources, err
+		}
+		if event == nil {
+			break
+		}
+		if event.Type == "SCALAR" {
+			sources.scalarEnds = append(sources.scalarEnds, [2]int{event.EndLine, event.EndCol + 1})
+		}
+		if event.Type != "DOCUMENT-START" {
+			continue
+		}
+		var directives []string
+		if event.VersionMajor != 0 || event.VersionMinor != 0 {
+			directives = append(directives, fmt.Sprintf("%%YAML %d.%d", event.VersionMajor, event.VersionMinor))
+		}
+		for _, directive := range event.TagDirectives {
+			directives = append(directives, fmt.Sprintf("%%TAG %s %s", directive.Handle, directive.Prefix))
+		}
+		sources.directives = append(sources.directives, directives)
+	}
+
+	return sources, nil
+}
+
+// nodeFormatter converts yaml.Node trees into NodeInfo trees
+type nodeFormatter struct {
+	sources  *nodeSources
+	profuse  bool
+	resolved bool
+	expand   bool
+	tagged   int
+	scalars  int
+	paths    map[*yaml.Node]string
+	expanded int
+	// expanding holds the alias targets currently being expanded
+	expanding []*yaml.Node
 }
 
 // FormatNode converts a YAML node into a NodeInfo structure
 func FormatNode(n yaml.Node) *NodeInfo {
+	return (&nodeFormatter{paths: nodePaths(&n)}).format(&n)
+}
+
+// format converts a YAML node into a NodeInfo structure.
+// Nodes must be visited in document order so that explicitly tagged nodes
+// and scalars line up with what the scanner and parser found.
+func (f *nodeFormatter) format(n *yaml.Node) *NodeInfo {
 	info := &NodeInfo{
 		Kind: formatKind(n.Kind),
 	}
@@ -30,9 +208,22 @@ func FormatNode(n yaml.Node) *NodeInfo {
 	if n.Anchor != "" {
 		info.Anchor = n.Anchor
 	}
-	if tag := formatTag(n.Tag); tag != "" {
+	if f.resolved {
+		info.Tag = n.Tag
+		if n.Tag != "" {
+			info.Tagging = formatTagging(n)
+		}
+	} else if tag := formatTag(n.Tag); tag != "" {
 		info.Tag = tag
 	}
+	if n.Style&yaml.TaggedStyle != 0 && f.sources != nil {
+		if f.tagged < len(f.sources.shorthands) {
+			if shorthand := f.sources.shorthands[f.tagged]; shorthand != n.Tag {
+				info.Shorthand = shorthand
+			}
+		}
+		f.tagged++
+	}
 	if n.HeadComment != "" {
 		info.Head = n.HeadComment
 	}
@@ -45,16 +236,107 @@ func FormatNode(n yaml.Node) *NodeInfo {
 
 	if info.Kind == "Scalar" {
 		info.Text = n.Value
-	} else if n.Content != nil {
+	}
+	if f.profuse {
+		info.Pos = fmt.Sprintf("%d;%d", n.Line, n.Column)
+		if n.Kind == yaml.ScalarNode && f.scalars < len(f.sources.scalarEnds) {
+			end := f.sources.scalarEnds[f.scalars]
+			info.Pos = formatPos(n.Line, n.Column, end[0], end[1])
+		}
+	}
+	if n.Kind == yaml.ScalarNode {
+		f.scalars++
+	}
+
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		info.Target = f.formatTarget(n)
+	}
+
+	if info.Kind != "Scalar" && n.Content != nil {
 		info.Content = make([]*NodeInfo, len(n.Content))
 		for i, node := range n.Content {
-			info.Content[i] = FormatNode(*node)
+			info.Content[i] = f.format(node)
 		}
 	}
 
 	return info
 }
 
+// formatTarget describes the node an alias refers to, expanding it when
+// requested
+func (f *nodeFormatter) formatTarget(n *yaml.Node) *TargetInfo {
+	target := n.Alias
+	info := &TargetInfo{
+		Anchor: n.Value,
+		Path:   f.paths[target],
+		Pos:    fmt.Sprintf("%d;%d", target.Line, target.Column),
+	}
+	if !f.expand {
+		return info
+	}
+
+	for _, outer := range f.expanding {
+		if outer == target {
+			info.Cycle = true
+			return info
+		}
+	}
+	if f.expanded >= maxExpandedNodes {
+		info.Truncated = true
+		return info
+	}
+
+	// The expanded copy is formatted without the tag and scalar
+	// bookkeeping, since those nodes were already counted where they
+	// appear in the source.
+	expander := &nodeFormatter{
+		profuse:   f.profuse,
+		resolved:  f.resolved,
+		expand:    true,
+		paths:     f.paths,
+		expanded:  f.expanded,
+		expanding: append(f.expanding, target),
+	}
+	info.Node = expander.formatCopy(target)
+	f.expanded = expander.expanded
+
+	return info
+}
+
+// formatCopy formats an aliased subtree for inline expansion
+func (f *nodeFormatter) formatCopy(n *yaml.Node) *NodeInfo {
+	f.expanded++
+	info := &NodeInfo{
+		Kind:   formatKind(n.Kind),
+		Style:  formatStyle(n.Style),
+		Anchor: n.Anchor,
+	}
+	if f.resolved {
+		info.Tag = n.Tag
+		if n.Tag != "" {
+			info.Tagging = formatTagging(n)
+		}
+	} else {
+		info.Tag = formatTag(n.Tag)
+	}
+	if n.Kind == yaml.ScalarNode {
+		info.Text = n.Value
+	}
+	if f.profuse {
+		info.Pos = fmt.Sprintf("%d;%d", n.Line, n.Column)
+	}
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		info.Target = f.formatTarget(n)
+	}
+	for _, child := range n.Content {
+		if f.expanded >= maxExpandedNodes {
+			break
+		}
+		info.Content = append(info.Content, f.formatCopy(child))
+	}
+	return info
+}
+
 // formatKind converts a YAML node kind into its string representation.
 func formatKind(k yaml.Kind) string {
 	switch k {
@@ -75,7 +357,8 @@ func formatKind(k yaml.Kind) string {
 
 // formatStyle converts a YAML node style into its string representation.
 func formatStyle(s yaml.Style) string {
-	switch s {
+	// TaggedStyle is reported separately, so look at the remaining bits
+	switch s &^ yaml.TaggedStyle {
 	case yaml.DoubleQuotedStyle:
 		return "Double"
 	case yaml.SingleQuotedStyle:
@@ -90,6 +373,15 @@ func formatStyle(s yaml.Style) string {
 	return ""
 }
 
+// formatTagging reports whether a node's tag was written in the source or
+// chosen by the resolver.
+func formatTagging(n *yaml.Node) string {
+	if n.Style&yaml.TaggedStyle != 0 {
+		return "explicit"
+	}
+	return "implicit"
+}
+
 // formatTag converts a YAML tag string to its string representation.
 func formatTag(tag string) string {
 	if tag == "!!str" || tag == "!!map" || tag == "!!seq" {
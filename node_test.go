@@ -79,3 +79,180 @@ settings:
 		}
 	}
 }
+
+// TestNodeModeDirectives tests that document directives and tag shorthands
+// are shown
+func TestNodeModeDirectives(t *testing.T) {
+	input := "%YAML 1.1\n%TAG !e! tag:example.com,2000:app/\n---\na: !e!foo x\nb: !<tag:v> y\nc: !!str 1\n"
+
+	stdout, stderr, err := runCommand(input, "-n")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no stderr, got %q", stderr)
+	}
+	expected := []string{
+		"directives:\n  - '%YAML 1.1'\n  - '%TAG !e! tag:example.com,2000:app/'\n",
+		"tag: tag:example.com,2000:app/foo\n        shorthand: '!e!foo'\n",
+		"tag: tag:v\n        shorthand: '!<tag:v>'\n",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+	if strings.Contains(stdout, "shorthand: '!!str'") {
+		t.Errorf("Expected no shorthand for standard tags, got %q", stdout)
+	}
+}
+
+// TestNodeModeDirectivesPerDocument tests that directives are attributed to
+// the document they belong to
+func TestNodeModeDirectivesPerDocument(t *testing.T) {
+	input := "a: 1\n...\n%TAG !x! tag:x/\n---\n!x!y b\n"
+
+	stdout, _, err := runCommand(input, "-n")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	docs := strings.Split(stdout, "\n---\n")
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %q", stdout)
+	}
+	if strings.Contains(docs[0], "directives:") {
+		t.Errorf("Expected no directives in first document, got %q", docs[0])
+	}
+	if !strings.Contains(docs[1], "'%TAG !x! tag:x/'") || !strings.Contains(docs[1], "shorthand: '!x!y'") {
+		t.Errorf("Expected directives in second document, got %q", docs[1])
+	}
+}
+
+// TestNodeProfuseMode tests that -N shows node positions, with end positions
+// for scalars
+func TestNodeProfuseMode(t *testing.T) {
+	input := "a: \"x\\ty\"\nb:\n  - [1, 2]\nc: |\n  l1\n  l2\n"
+
+	stdout, stderr, err := runCommand(input, "-N")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no stderr, got %q", stderr)
+	}
+	expected := []string{
+		"kind: Document\npos: 1;1\n",
+		"  - kind: Mapping\n    pos: 1;1\n",
+		"text: \"x\\ty\"\n        pos: 1;4-1;10\n",
+		"- kind: Sequence\n        pos: 3;3\n",
+		"style: Flow\n            pos: 3;5\n",
+		"text: \"2\"\n                pos: 3;9-3;10\n",
+		"text: |\n          l1\n          l2\n        pos: 4;4-7;1\n",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+}
+
+// TestNodeModeHasNoPositions tests that -n does not show positions
+func TestNodeModeHasNoPositions(t *testing.T) {
+	stdout, _, err := runCommand("a: b", "-n")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if strings.Contains(stdout, "pos:") {
+		t.Errorf("Expected no positions, got %q", stdout)
+	}
+}
+
+// TestNodeModeResolvedTags tests that -r shows every node's resolved tag and
+// whether it was explicit
+func TestNodeModeResolvedTags(t *testing.T) {
+	input := "a: on\nb: 0o17\nc: 1e3\nd: ~\ne: !!str 5\nf: !!str \"x\"\n"
+
+	stdout, stderr, err := runCommand(input, "-n", "-r")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no stderr, got %q", stderr)
+	}
+	expected := []string{
+		"tag: '!!map'\n    tagging: implicit\n",
+		"tag: '!!str'\n        tagging: implicit\n        text: \"on\"\n",
+		"tag: '!!int'\n        tagging: implicit\n        text: \"0o17\"\n",
+		"tag: '!!float'\n        tagging: implicit\n        text: \"1e3\"\n",
+		"tag: '!!null'\n        tagging: implicit\n        text: \"~\"\n",
+		"tag: '!!str'\n        tagging: explicit\n        text: \"5\"\n",
+		"style: Double\n        tag: '!!str'\n        tagging: explicit\n        text: x\n",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+}
+
+// TestNodeModeHidesDefaultTags tests that -n still hides the default tags
+// without -r
+func TestNodeModeHidesDefaultTags(t *testing.T) {
+	stdout, _, err := runCommand("a: [b]", "-n")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	for _, tag := range []string{"'!!str'", "'!!map'", "'!!seq'", "tagging:"} {
+		if strings.Contains(stdout, tag) {
+			t.Errorf("Expected output not to contain %q, got %q", tag, stdout)
+		}
+	}
+}
+
+// TestNodeModeAliasTarget tests that aliases show the anchor, path and
+// position of the node they refer to
+func TestNodeModeAliasTarget(t *testing.T) {
+	input := "jobs:\n  build: &defaults\n    image: go\n  test: *defaults\n  list: [&x 1, \"a b\": 2]\nref: *x\n"
+
+	stdout, stderr, err := runCommand(input, "-n")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no stderr, got %q", stderr)
+	}
+	expected := []string{
+		"target:\n              anchor: defaults\n              path: $.jobs.build\n              pos: 2;10\n",
+		"target:\n          anchor: x\n          path: $.jobs.list[0]\n          pos: 5;10\n",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+	if strings.Contains(stdout, "node:") {
+		t.Errorf("Expected no expanded targets without -x, got %q", stdout)
+	}
+}
+
+// TestNodeModeExpandAliases tests that -x inlines alias targets and stops
+// at cycles
+func TestNodeModeExpandAliases(t *testing.T) {
+	stdout, _, err := runCommand("a: &a {b: c}\nd: *a\n", "-n", "-x")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := "node:\n            kind: Mapping\n            style: Flow\n            anchor: a\n" +
+		"            content:\n              - kind: Scalar\n                text: b\n"
+	if !strings.Contains(stdout, expected) {
+		t.Errorf("Expected output to contain %q, got %q", expected, stdout)
+	}
+
+	stdout, _, err = runCommand("&a [*a]\n", "-n", "--expand")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if strings.Count(stdout, "cycle: true") != 1 {
+		t.Errorf("Expected one cycle marker, got %q", stdout)
+	}
+}
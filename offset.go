@@ -0,0 +1,83 @@
+// Package main provides source offset utilities for the go-yaml tool.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// OffsetMap converts the character indexes reported in go-yaml marks into
+// byte offsets of the original input
+type OffsetMap struct {
+	offsets []int
+}
+
+// NewOffsetMap builds an OffsetMap for the given input.
+// go-yaml counts characters after any byte order mark, so the map starts
+// after the BOM and steps over UTF-16 code units when the input uses them.
+func NewOffsetMap(input []byte) *OffsetMap {
+	m := &OffsetMap{}
+
+	switch {
+	case bytes.HasPrefix(input, []byte{0xFF, 0xFE}):
+		m.addUTF16(input, 2, false)
+	case bytes.HasPrefix(input, []byte{0xFE, 0xFF}):
+		m.addUTF16(input, 2, true)
+	case bytes.HasPrefix(input, []byte{0xEF, 0xBB, 0xBF}):
+		m.addUTF8(input, 3)
+	default:
+		m.addUTF8(input, 0)
+	}
+
+	return m
+}
+
+// addUTF8 records the byte offset of every character of UTF-8 input
+func (m *OffsetMap) addUTF8(input []byte, pos int) {
+	for pos < len(input) {
+		m.offsets = append(m.offsets, pos)
+		_, size := utf8.DecodeRune(input[pos:])
+		pos += size
+	}
+	m.offsets = append(m.offsets, pos)
+}
+
+// addUTF16 records the byte offset of every character of UTF-16 input
+func (m *OffsetMap) addUTF16(input []byte, pos int, bigEndian bool) {
+	unit := func(i int) uint16 {
+		if bigEndian {
+			return uint16(input[i])<<8 | uint16(input[i+1])
+		}
+		return uint16(input[i+1])<<8 | uint16(input[i])
+	}
+	for pos+1 < len(input) {
+		m.offsets = append(m.offsets, pos)
+		if u := unit(pos); u >= 0xD800 && u < 0xDC00 && pos+3 < len(input) {
+			// A high surrogate and its low surrogate form one character
+			pos += 4
+		} else {
+			pos += 2
+		}
+	}
+	m.offsets = append(m.offsets, len(input))
+}
+
+// ByteOffset returns the byte offset of the character at the given index
+func (m *OffsetMap) ByteOffset(index int) int {
+	if index < 0 {
+		return 0
+	}
+	if index >= len(m.offsets) {
+		return m.offsets[len(m.offsets)-1]
+	}
+	return m.offsets[index]
+}
+
+// formatOffset renders a start/end offset as "start" or "start-end"
+func formatOffset(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d-%d", start, end)
+}
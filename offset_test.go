@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestProfuseOffsets tests that profuse modes report byte and character
+// offsets alongside line and column
+func TestProfuseOffsets(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		flags    []string
+		expected []string
+	}{
+		{
+			"ascii tokens",
+			"a: b\n",
+			[]string{"-T"},
+			[]string{"{Token: SCALAR, Value: b, Pos: 1;4-1;5, Offset: 3-4, Index: 3-4}"},
+		},
+		{
+			"multibyte tokens",
+			"ä: \"ö\"\n",
+			[]string{"-T"},
+			[]string{
+				"{Token: SCALAR, Value: ä, Pos: 1;1-1;2, Offset: 0-2, Index: 0-1}",
+				"{Token: SCALAR, Value: ö, Style: Double, Pos: 1;4-1;7, Offset: 4-8, Index: 3-6}",
+			},
+		},
+		{
+			"multibyte events",
+			"- 日本\n- x\n",
+			[]string{"-E"},
+			[]string{"{Event: SCALAR, Value: x, Implicit: plain, Pos: 2;3-2;4, Offset: 11-12, Index: 7-8}"},
+		},
+		{
+			"byte order mark",
+			"\xef\xbb\xbfa: 1\n",
+			[]string{"-E"},
+			[]string{"{Event: SCALAR, Value: a, Implicit: plain, Pos: 1;1-1;2, Offset: 3-4, Index: 0-1}"},
+		},
+		{
+			"long mode",
+			"a: b\n",
+			[]string{"-E", "-l"},
+			[]string{"  Offset: 3-4\n  Index: 3-4\n"},
+		},
+		{
+			"error offset",
+			"é: 1\n  b: 2\n",
+			[]string{"-T"},
+			[]string{"{Error: scanner, Problem: mapping values are not allowed in this context, Pos: 2;4, Offset: 9}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, _, _ := runCommand(tt.input, tt.flags...)
+			for _, expected := range tt.expected {
+				if !strings.Contains(stdout, expected) {
+					t.Errorf("Expected output to contain %q, got %q", expected, stdout)
+				}
+			}
+		})
+	}
+}
+
+// TestCompactModesHaveNoOffsets tests that offsets are only shown in
+// profuse modes
+func TestCompactModesHaveNoOffsets(t *testing.T) {
+	for _, flag := range []string{"-t", "-e"} {
+		stdout, _, err := runCommand("a: b\n", flag)
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if strings.Contains(stdout, "Offset:") || strings.Contains(stdout, "Index:") {
+			t.Errorf("Expected no offsets for %s, got %q", flag, stdout)
+		}
+	}
+}
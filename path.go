@@ -0,0 +1,71 @@
+// Package main provides YAML path utilities for the go-yaml tool.
+package main
+
+import (
+	"regexp"
+	"strconv"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// plainPathKey matches mapping keys that can be written as .key in a path
+var plainPathKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// pathRoot is the path of a document's root node
+const pathRoot = "$"
+
+// pathKey returns the path of a mapping value from its mapping's path and key
+func pathKey(path string, key *yaml.Node) string {
+	switch key.Kind {
+	case yaml.ScalarNode:
+		if plainPathKey.MatchString(key.Value) {
+			return path + "." + key.Value
+		}
+		return path + "[" + strconv.Quote(key.Value) + "]"
+	case yaml.MappingNode:
+		return path + "[{...}]"
+	case yaml.SequenceNode:
+		return path + "[[...]]"
+	case yaml.AliasNode:
+		return path + "[*" + key.Value + "]"
+	}
+	return path + "[?]"
+}
+
+// pathIndex returns the path of a sequence item from its sequence's path
+func pathIndex(path string, index int) string {
+	return path + "[" + strconv.Itoa(index) + "]"
+}
+
+// nodePaths maps every node of a document to its path from the document
+// root. Mapping keys get the path of their value with a " (key)" suffix.
+func nodePaths(doc *yaml.Node) map[*yaml.Node]string {
+	paths := make(map[*yaml.Node]string)
+
+	var walk func(n *yaml.Node, path string)
+	walk = func(n *yaml.Node, path string) {
+		if _, seen := paths[n]; seen {
+			return
+		}
+		paths[n] = path
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, child := range n.Content {
+				walk(child, path)
+			}
+		case yaml.SequenceNode:
+			for i, child := range n.Content {
+				walk(child, pathIndex(path, i))
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				valuePath := pathKey(path, n.Content[i])
+				walk(n.Content[i], valuePath+" (key)")
+				walk(n.Content[i+1], valuePath)
+			}
+		}
+	}
+	walk(doc, pathRoot)
+
+	return paths
+}
@@ -0,0 +1,384 @@
+// Package main provides error reporting utilities for the go-yaml tool.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Failure kinds
+const (
+	KindUsage   = "usage"
+	KindIO      = "io"
+	KindScan    = "scan"
+	KindParse   = "parse"
+	KindCompose = "compose"
+	KindDecode  = "decode"
+	KindEncode  = "encode"
+
+	// KindFormat marks an input that --check finds would change when it is
+	// re-emitted
+	KindFormat = "format"
+
+	// KindWarning marks reports that do not fail the run
+	KindWarning = "warning"
+)
+
+// exitCodes gives every failure kind its own exit status
+var exitCodes = map[string]int{
+	KindFormat:  1,
+	KindUsage:   2,
+	KindIO:      3,
+	KindScan:    4,
+	KindParse:   5,
+	KindCompose: 6,
+	KindDecode:  7,
+	KindEncode:  8,
+}
+
+// Failure describes an error for reporting on stderr
+type Failure struct {
+	Kind     string `json:"kind" yaml:"kind"`
+	Message  string `json:"message" yaml:"message"`
+	File     string `json:"file,omitempty" yaml:"file,omitempty"`
+	Path     string `json:"path,omitempty" yaml:"path,omitempty"`
+	Line     int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Column   int    `json:"column,omitempty" yaml:"column,omitempty"`
+	Snippet  string `json:"snippet,omitempty" yaml:"snippet,omitempty"`
+	Document *int   `json:"document,omitempty" yaml:"document,omitempty"`
+}
+
+// kindError marks an error with its failure kind when the kind cannot be
+// told from the error itself
+type kindError struct {
+	kind string
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+
+func (e *kindError) Unwrap() error { return e.err }
+
+// conversionError is a failure to convert the node at a YAML path into
+// another format
+type conversionError struct {
+	kind    string
+	path    string
+	line    int
+	column  int
+	message string
+}
+
+func (e *conversionError) Error() string {
+	return fmt.Sprintf("%s (line %d, column %d): %s", e.path, e.line, e.column, e.message)
+}
+
+// lineNumber finds the line number in go-yaml decoder messages
+var lineNumber = regexp.MustCompile(`^line (\d+): `)
+
+// unknownAnchor finds the anchor name in go-yaml unknown anchor messages
+var unknownAnchor = regexp.MustCompile(`unknown anchor '(.*)' referenced`)
+
+// composeProblem matches the decoder messages about anchors and aliases,
+// which are found while composing the node graph rather than while
+// converting it to Go values
+var composeProblem = regexp.MustCompile(`unknown anchor|value contains itself|excessive aliasing`)
+
+// newFailure classifies an error returned while processing input.
+// The decoder reports scanner and parser errors as plain text, so the input
+// is parsed again to recover their kind and position, and to find which
+// document an error is in.
+func newFailure(err error, input []byte) *Failure {
+	var kindErr *kindError
+	if errors.As(err, &kindErr) {
+		return &Failure{Kind: kindErr.kind, Message: kindErr.err.Error()}
+	}
+
+	source := indexSource(input)
+	failure := &Failure{}
+
+	var convErr *conversionError
+	var parserErr *yaml.ParserError
+	var typeErr *yaml.TypeError
+	switch {
+	case errors.As(err, &convErr):
+		failure.Kind = convErr.kind
+		failure.Message = convErr.message
+		failure.Path = convErr.path
+		failure.Line = convErr.line
+		failure.Column = convErr.column
+	case errors.As(err, &parserErr) || sameSyntaxError(err, source.err):
+		if parserErr == nil {
+			parserErr = source.err
+		}
+		failure.Kind = KindParse
+		if parserErr.Kind != "parser" {
+			failure.Kind = KindScan
+		}
+		failure.Message = parserErr.Problem
+		if parserErr.Context != "" {
+			failure.Message += " (" + parserErr.Context + ")"
+		}
+		if parserErr.Kind != "reader" {
+			failure.Line = parserErr.ProblemLine
+			failure.Column = parserErr.ProblemCol + 1
+		}
+	case errors.As(err, &typeErr):
+		failure.Kind = KindDecode
+		failure.Message = strings.Join(typeErr.Errors, "; ")
+		if m := lineNumber.FindStringSubmatch(typeErr.Errors[0]); m != nil {
+			failure.Line, _ = strconv.Atoi(m[1])
+		}
+	default:
+		failure.Message = err.Error()
+		if i := strings.LastIndex(failure.Message, "yaml: "); i >= 0 {
+			failure.Message = failure.Message[i+len("yaml: "):]
+		}
+		failure.Kind = KindDecode
+		if composeProblem.MatchString(failure.Message) {
+			failure.Kind = KindCompose
+		}
+		if m := lineNumber.FindStringSubmatch(failure.Message); m != nil {
+			failure.Line, _ = strconv.Atoi(m[1])
+		} else if m := unknownAnchor.FindStringSubmatch(failure.Message); m != nil {
+			if alias, ok := source.unknownAliases[m[1]]; ok {
+				failure.Line = alias.StartLine
+				failure.Column = alias.StartCol + 1
+			}
+		}
+	}
+
+	addSourceContext(failure, input, source)
+	return failure
+}
+
+// sameSyntaxError reports whether err is the decoder's report of the syntax
+// error found by indexSource. The source is parsed to its end, so its error
+// may be in a later document than the one that failed to decode.
+func sameSyntaxError(err error, syntaxErr *yaml.ParserError) bool {
+	return syntaxErr != nil && strings.Contains(err.Error(), syntaxErr.Problem)
+}
+
+// addSourceContext fills in the offending line and the document index of a
+// failure with a known line
+func addSourceContext(failure *Failure, input []byte, source *sourceIndex) {
+	if failure.Line <= 0 {
+		return
+	}
+	if lines := sourceLines(input); failure.Line <= len(lines) {
+		failure.Snippet = lines[failure.Line-1]
+	}
+	document := source.documentAt(failure.Line)
+	failure.Document = &document
+}
+
+// sourceIndex is what parsing events tells about the input
+type sourceIndex struct {
+	documentLines  []int
+	unknownAliases map[string]*yaml.Event
+	err            *yaml.ParserError
+}
+
+// indexSource records where each document starts and which aliases refer
+// to undefined anchors, stopping at the first scanner or parser error
+func indexSource(input []byte) *sourceIndex {
+	source := &sourceIndex{unknownAliases: make(map[string]*yaml.Event)}
+
+	parser, err := yaml.NewEventParser(bytes.NewReader(input))
+	if err != nil {
+		return source
+	}
+	defer parser.Close()
+
+	anchors := make(map[string]bool)
+	for {
+		event, err := parser.Next()
+		if err != nil {
+			errors.As(err, &source.err)
+			return source
+		}
+		if event == nil {
+			return source
+		}
+
+		switch EventType(event.Type) {
+		case EventDocumentStart:
+			source.documentLines = append(source.documentLines, event.StartLine)
+			anchors = make(map[string]bool)
+		case EventAlias:
+			if _, seen := source.unknownAliases[event.Anchor]; !seen && !anchors[event.Anchor] {
+				source.unknownAliases[event.Anchor] = event
+			}
+		}
+		if event.Anchor != "" && EventType(event.Type) != EventAlias {
+			anchors[event.Anchor] = true
+		}
+	}
+}
+
+// documentAt returns the index of the document containing a line
+func (s *sourceIndex) documentAt(line int) int {
+	document := 0
+	for i, start := range s.documentLines {
+		if start <= line {
+			document = i
+		}
+	}
+	return document
+}
+
+// reporter writes failures to stderr in the chosen format and keeps the exit
+// code of the first one
+type reporter struct {
+	format string
+	style  YAMLStyle
+	code   int
+}
+
+// newReporter returns a reporter for an --error-format value
+func newReporter(format string) (*reporter, error) {
+	switch format {
+	case "text", "json", "yaml":
+		return &reporter{format: format}, nil
+	}
+	return nil, fmt.Errorf("unknown error format %q (use json, yaml or text)", format)
+}
+
+// report writes a failure
+func (r *reporter) report(f *Failure) {
+	if r.code == 0 {
+		r.code = exitCodes[f.Kind]
+	}
+	writeFailure(os.Stderr, f, r.format, r.style)
+}
+
+// warn reports a warning, which does not change the exit code
+func (r *reporter) warn(f *Failure) {
+	writeFailure(os.Stderr, f, r.format, r.style)
+}
+
+// usage reports a usage error and exits
+func (r *reporter) usage(message string) {
+	r.report(&Failure{Kind: KindUsage, Message: message})
+	os.Exit(r.code)
+}
+
+// exit ends the program with the exit code of the first failure, if any
+func (r *reporter) exit() {
+	if r.code != 0 {
+		os.Exit(r.code)
+	}
+}
+
+// writeFailure writes a failure as a JSON line, a one-item YAML sequence or
+// text with the offending line
+func writeFailure(w io.Writer, f *Failure, format string, style YAMLStyle) {
+	switch format {
+	case "json":
+		data, _ := json.Marshal(f)
+		fmt.Fprintf(w, "%s\n", data)
+	case "yaml":
+		var buf bytes.Buffer
+		enc := newYAMLEncoder(&buf, style)
+		enc.Encode([]*Failure{f})
+		enc.Close()
+		fmt.Fprint(w, buf.String())
+	default:
+		if f.Kind == KindUsage {
+			fmt.Fprintf(w, "Error: %s\n", f.Message)
+			return
+		}
+		location := f.File
+		if location == "" {
+			location = "<stdin>"
+		}
+		if f.Line > 0 {
+			location += fmt.Sprintf(":%d", f.Line)
+			if f.Column > 0 {
+				location += fmt.Sprintf(":%d", f.Column)
+			}
+		}
+		document := ""
+		if f.Document != nil {
+			document = fmt.Sprintf(" in document %d", *f.Document)
+		}
+		path := ""
+		if f.Path != "" {
+			path = f.Path + ": "
+		}
+		if f.Kind == KindWarning {
+			fmt.Fprintf(w, "Warning: %s%s: %s%s\n", location, document, path, f.Message)
+			return
+		}
+		fmt.Fprintf(w, "Error: %s: %s error%s: %s%s\n", location, f.Kind, document, path, f.Message)
+		if f.Snippet != "" {
+			fmt.Fprintf(w, "  %s\n", f.Snippet)
+			if f.Column > 0 {
+				fmt.Fprintf(w, "  %s^\n", snippetPad(f.Snippet, f.Column))
+			}
+		}
+	}
+}
+
+// snippetPad returns the whitespace that puts a caret under a column of a
+// snippet, copying tabs and taking the display width of wide characters so
+// that it lines up
+func snippetPad(snippet string, column int) string {
+	var pad strings.Builder
+	runes := []rune(snippet)
+	for i := 0; i < column-1; i++ {
+		switch {
+		case i >= len(runes):
+			pad.WriteRune(' ')
+		case runes[i] == '\t':
+			pad.WriteRune('\t')
+		default:
+			pad.WriteString(strings.Repeat(" ", runeWidth(runes[i])))
+		}
+	}
+	return pad.String()
+}
+
+// wideRanges lists the East Asian wide and fullwidth characters, which
+// terminals show in two columns
+var wideRanges = []struct{ first, last rune }{
+	{0x1100, 0x115F},   // Hangul Jamo initials
+	{0x2E80, 0x303E},   // CJK radicals, Kangxi radicals and CJK punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, Bopomofo and CJK compatibility
+	{0x3400, 0x4DBF},   // CJK extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE30, 0xFE4F},   // CJK compatibility forms
+	{0xFF00, 0xFF60},   // Fullwidth forms
+	{0xFFE0, 0xFFE6},   // Fullwidth signs
+	{0x1F300, 0x1F64F}, // Pictographs and emoticons
+	{0x1F900, 0x1F9FF}, // Supplemental pictographs
+	{0x20000, 0x3FFFD}, // CJK extensions B and later
+}
+
+// runeWidth returns the number of columns a terminal shows a character in:
+// none for combining marks and format characters, two for wide characters
+// and one otherwise
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, wide := range wideRanges {
+		if r >= wide.first && r <= wide.last {
+			return 2
+		}
+	}
+	return 1
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// exitCode returns the exit status of a finished command
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return 0
+}
+
+// TestErrorKindsAndExitCodes tests that each class of failure is reported
+// with its kind and exit code
+func TestErrorKindsAndExitCodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		args  []string
+		kind  string
+		code  int
+	}{
+		{"usage", "a: 1", []string{"json", "--error-format=json", "--profuse"}, "usage", 2},
+		{"io", "", []string{"json", "--error-format=json", "missing.yaml"}, "io", 3},
+		{"scan", "a: b\n  c: d\n", []string{"-j", "--error-format=json"}, "scan", 4},
+		{"parse", "a: [1\n", []string{"-j", "--error-format=json"}, "parse", 5},
+		{"compose", "a: *x\n", []string{"-j", "--error-format=json"}, "compose", 6},
+		{"decode", "a: !!int foo\n", []string{"-j", "--error-format=json"}, "decode", 7},
+		{"encode", "a: .nan\n", []string{"-j", "--error-format=json"}, "encode", 8},
+		// A syntax error in a later document does not hide the first error
+		{"compose before parse", "a: *x\n---\nb: [\n", []string{"-j", "--error-format=json"}, "compose", 6},
+		{"decode before parse", "a: !!int foo\n---\nb: [\n", []string{"-y", "--error-format=json"}, "decode", 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, err := runCommand(tt.input, tt.args...)
+			if code := exitCode(err); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d (%v)", tt.code, code, err)
+			}
+			var failure Failure
+			if err := json.Unmarshal([]byte(stderr), &failure); err != nil {
+				t.Fatalf("Expected a JSON error, got %q: %v", stderr, err)
+			}
+			if failure.Kind != tt.kind {
+				t.Errorf("Expected kind %q, got %q", tt.kind, failure.Kind)
+			}
+			if failure.Message == "" {
+				t.Errorf("Expected a message, got %q", stderr)
+			}
+		})
+	}
+}
+
+// TestErrorPositionAndDocument tests the position, snippet and document
+// index of a failure in each error format
+func TestErrorPositionAndDocument(t *testing.T) {
+	input := "a: 1\n---\nb: 2\nc: *x\n"
+
+	_, stderr, _ := runCommand(input, "-j", "--error-format", "json")
+	expected := `{"kind":"compose","message":"unknown anchor 'x' referenced","line":4,"column":4,"snippet":"c: *x","document":1}` + "\n"
+	if stderr != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr)
+	}
+
+	_, stderr, _ = runCommand(input, "-j", "--error-format", "yaml")
+	expected = "- kind: compose\n  message: unknown anchor 'x' referenced\n  line: 4\n  column: 4\n  snippet: 'c: *x'\n  document: 1\n"
+	if stderr != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr)
+	}
+
+	_, stderr, _ = runCommand(input, "-j")
+	expected = "Error: <stdin>:4:4: compose error in document 1: unknown anchor 'x' referenced\n  c: *x\n     ^\n"
+	if stderr != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr)
+	}
+}
+
+// TestErrorFormatValidated tests that an unknown error format is a usage
+// error
+func TestErrorFormatValidated(t *testing.T) {
+	_, stderr, err := runCommand("a: 1", "-j", "--error-format=xml")
+	if code := exitCode(err); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(stderr, `unknown error format "xml"`) {
+		t.Errorf("Expected error message, got %q", stderr)
+	}
+}
+
+// TestErrorBeforeLaterSyntaxError tests that an error in the first document
+// is reported there, not as the syntax error of a later document
+func TestErrorBeforeLaterSyntaxError(t *testing.T) {
+	_, stderr, err := runCommand("a: *x\n---\nb: [\n", "-j")
+	if code := exitCode(err); code != 6 {
+		t.Errorf("Expected exit code 6, got %d", code)
+	}
+	if !strings.HasPrefix(stderr, "Error: <stdin>:1:4: compose error in document 0: unknown anchor 'x' referenced") {
+		t.Errorf("Expected compose error in document 0, got %q", stderr)
+	}
+}
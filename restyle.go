@@ -0,0 +1,179 @@
+// Package main provides YAML style rewriting utilities for the go-yaml tool.
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// restyleNames lists the styles a rule can apply
+var restyleNames = []string{"double", "single", "plain", "literal", "flow", "block"}
+
+// defaultFlowWidth is the width a flow sequence must fit in when no --width
+// is set, the line width libyaml emitters use by default
+const defaultFlowWidth = 80
+
+// styleRule rewrites the style of the nodes at or below a path
+type styleRule struct {
+	path  string
+	style string
+	match *regexp.Regexp
+}
+
+// parseStyleRules parses a --restyle value: a comma separated list of rules
+// written as style or path=style. A rule without a path applies to the whole
+// document, and * in a path matches any one key or index.
+func parseStyleRules(spec string) ([]styleRule, error) {
+	var rules []styleRule
+	for _, item := range splitRules(spec) {
+		path, style := pathRoot, item
+		if i := strings.LastIndex(item, "="); i >= 0 {
+			path, style = item[:i], item[i+1:]
+		}
+		if style == "" || !validPolicy(style, restyleNames) {
+			return nil, fmt.Errorf("unknown style %q (use %s)", style, strings.Join(restyleNames, ", "))
+		}
+		if !strings.HasPrefix(path, pathRoot) {
+			return nil, fmt.Errorf("bad path %q in style rule (paths start with %s)", path, pathRoot)
+		}
+		rules = append(rules, styleRule{path: path, style: style, match: pathPattern(path)})
+	}
+	return rules, nil
+}
+
+// splitRules splits spec at the commas that are not inside the brackets of
+// a path, such as $["a,b"]
+func splitRules(spec string) []string {
+	if spec == "" {
+		return nil
+	}
+	var items []string
+	depth, start := 0, 0
+	for i, c := range spec {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, spec[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(items, spec[start:])
+}
+
+// pathPattern returns a pattern matching path and every path below it. A *
+// segment matches any one key or index.
+func pathPattern(path string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(path)
+	pattern = strings.ReplaceAll(pattern, `\[\*\]`, `\[[^\]]*\]`)
+	pattern = strings.ReplaceAll(pattern, `\.\*`, `(\.[^.\[ ]+|\[[^\]]*\])`)
+	return regexp.MustCompile(`^` + pattern + `($|[.\[])`)
+}
+
+// restyle applies rules, in order, to every node of a document. Mapping keys
+// are left as they are written. Sequences only become flow sequences when
+// they fit in width columns, or defaultFlowWidth when width is zero.
+func restyle(doc *yaml.Node, rules []styleRule, width int) {
+	if len(rules) == 0 {
+		return
+	}
+	if width == 0 {
+		width = defaultFlowWidth
+	}
+	for node, path := range nodePaths(doc) {
+		if strings.HasSuffix(path, " (key)") {
+			continue
+		}
+		for _, rule := range rules {
+			if rule.match.MatchString(path) {
+				restyleNode(node, rule.style, width)
+			}
+		}
+	}
+}
+
+// restyleNode applies a style to one node. Each style only changes the
+// nodes it suits: quoting applies to strings, literal to multi-line strings,
+// flow to short sequences and block to flow collections. An explicit tag is
+// kept.
+func restyleNode(node *yaml.Node, style string, width int) {
+	tagged := node.Style & yaml.TaggedStyle
+	isString := node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str"
+	multiLine := strings.Contains(node.Value, "\n")
+
+	switch style {
+	case "double":
+		if isString {
+			node.Style = tagged | yaml.DoubleQuotedStyle
+		}
+	case "single":
+		if isString {
+			node.Style = tagged | yaml.SingleQuotedStyle
+		}
+	case "plain":
+		if isString && !multiLine && plainString(node.Value) {
+			node.Style = tagged
+		}
+	case "literal":
+		if isString && multiLine {
+			node.Style = tagged | yaml.LiteralStyle
+		}
+	case "flow":
+		if node.Kind == yaml.SequenceNode && shortSequence(node, width) {
+			node.Style |= yaml.FlowStyle
+		}
+	case "block":
+		if node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
+			node.Style &^= yaml.FlowStyle
+		}
+	}
+}
+
+// plainString reports whether a string can be written without quotes and
+// still read back as the same string
+func plainString(value string) bool {
+	out, err := yaml.Marshal(value)
+	return err == nil && string(out) == value+"\n"
+}
+
+// shortSequence reports whether a sequence holds only single-line scalars
+// and aliases without comments, and whether it fits in width columns once
+// written as a flow sequence at its column
+func shortSequence(node *yaml.Node, width int) bool {
+	// The sequence is written after its key, at about the column it had
+	length := node.Column + len("[]")
+	for i, item := range node.Content {
+		switch {
+		case item.Kind != yaml.ScalarNode && item.Kind != yaml.AliasNode:
+			return false
+		case strings.Contains(item.Value, "\n"):
+			return false
+		case item.HeadComment != "" || item.LineComment != "" || item.FootComment != "":
+			return false
+		}
+		if i > 0 {
+			length += len(", ")
+		}
+		length += len(flowItem(item))
+	}
+	return length <= width
+}
+
+// flowItem returns the text of a sequence item as it is written
+func flowItem(item *yaml.Node) string {
+	if item.Kind == yaml.AliasNode {
+		return "*" + item.Value
+	}
+	out, err := yaml.Marshal(item)
+	if err != nil {
+		return item.Value
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRestyle tests the style rules of --restyle on the preserve path
+func TestRestyle(t *testing.T) {
+	input := `# config
+name: 'demo'
+tags:
+  - a
+  - 'b'
+env: {KEY: "v"}
+steps:
+  - run: "echo hi\necho there\n"
+    args: [x, "--y"]
+  - run: 'single'
+`
+	tests := []struct {
+		name     string
+		rules    string
+		expected string
+	}{
+		{
+			"double quoted strings",
+			"double",
+			"# config\nname: \"demo\"\ntags:\n  - \"a\"\n  - \"b\"\nenv: {KEY: \"v\"}\nsteps:\n  - run: \"echo hi\\necho there\\n\"\n    args: [\"x\", \"--y\"]\n  - run: \"single\"\n",
+		},
+		{
+			"unquoted, literal and flow",
+			"plain,literal,flow",
+			"# config\nname: demo\ntags: [a, b]\nenv: {KEY: v}\nsteps:\n  - run: |\n      echo hi\n      echo there\n    args: [x, --y]\n  - run: single\n",
+		},
+		{
+			"by path",
+			"$.steps[*].run=literal,$.steps.*.args=block,$.name=double",
+			"# config\nname: \"demo\"\ntags:\n  - a\n  - 'b'\nenv: {KEY: \"v\"}\nsteps:\n  - run: |\n      echo hi\n      echo there\n    args:\n      - x\n      - \"--y\"\n  - run: 'single'\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCommand(input, "yaml", "--preserve", "--restyle="+tt.rules)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v: %s", err, stderr)
+			}
+			if stdout != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, stdout)
+			}
+		})
+	}
+}
+
+// TestRestyleFlowWidth tests that flow only folds sequences that fit in the
+// line width, 80 columns unless --width is set
+func TestRestyleFlowWidth(t *testing.T) {
+	input := "short:\n  - a\n  - b\nlong:\n  - alpha-alpha-alpha\n  - beta-beta-beta-beta\n  - gamma-gamma-gamma-gamma\n  - delta-delta-delta-delta\n"
+	tests := []struct {
+		flags    []string
+		expected string
+	}{
+		{
+			[]string{"-Y", "--restyle=flow"},
+			"short: [a, b]\nlong:\n  - alpha-alpha-alpha\n  - beta-beta-beta-beta\n  - gamma-gamma-gamma-gamma\n  - delta-delta-delta-delta\n",
+		},
+		{
+			[]string{"-Y", "--restyle=flow", "--width=120"},
+			"short: [a, b]\nlong: [alpha-alpha-alpha, beta-beta-beta-beta, gamma-gamma-gamma-gamma, delta-delta-delta-delta]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.flags, " "), func(t *testing.T) {
+			stdout, stderr, err := runCommand(input, tt.flags...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v: %s", err, stderr)
+			}
+			if stdout != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, stdout)
+			}
+		})
+	}
+}
+
+// TestRestyleErrors tests that bad style rules are usage errors
+func TestRestyleErrors(t *testing.T) {
+	tests := []struct {
+		flags    []string
+		expected string
+	}{
+		{[]string{"yaml", "--preserve", "--restyle=bold"}, `unknown style "bold"`},
+		{[]string{"-Y", "--restyle=a.b=flow"}, `bad path "a.b"`},
+		{[]string{"-y", "--restyle=double"}, "--restyle needs the node tree of --preserve or --normalize"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.flags, " "), func(t *testing.T) {
+			_, stderr, err := runCommand("a: 1", tt.flags...)
+			if code := exitCode(err); code != 2 {
+				t.Errorf("Expected exit code 2, got %d", code)
+			}
+			if !strings.Contains(stderr, tt.expected) {
+				t.Errorf("Expected %q in stderr, got %q", tt.expected, stderr)
+			}
+		})
+	}
+}
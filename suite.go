@@ -0,0 +1,454 @@
+// Package main provides the yaml-test-suite conformance runner of the
+// go-yaml tool.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Results of a suite check
+const (
+	suitePass = "pass"
+	suiteFail = "FAIL"
+	suiteSkip = "-"
+)
+
+// suiteChecks names the checks made for each case, in matrix order
+var suiteChecks = []string{"events", "json", "yaml"}
+
+// suiteCase is one test of a yaml-test-suite checkout: a directory with an
+// in.yaml file and the expected results next to it
+type suiteCase struct {
+	id   string
+	name string
+	dir  string
+}
+
+// suiteResult is the outcome of each check of a case, with a diff for each
+// failing check
+type suiteResult struct {
+	id      string
+	name    string
+	results map[string]string
+	diffs   map[string]string
+}
+
+// runSuite runs the `go-yaml suite <dir>` command, which runs go-yaml on
+// every case of a yaml-test-suite checkout and prints a pass/fail matrix.
+// With a baseline, only the checks that used to pass are expected to pass.
+func runSuite(args []string) {
+	fs := flag.NewFlagSet("suite", flag.ExitOnError)
+	fs.Usage = printSuiteHelp
+	baseline := fs.String("baseline", "", "Results file to compare with")
+	update := fs.Bool("update-baseline", false, "Write the results to the baseline file")
+	errorFormat := fs.String("error-format", "text", "Error output format: json, yaml or text")
+	fs.Parse(args)
+
+	rep, err := newReporter(*errorFormat)
+	if err != nil {
+		rep = &reporter{format: "text"}
+		rep.usage(err.Error())
+	}
+	if fs.NArg() != 1 {
+		rep.usage("suite needs the directory of a yaml-test-suite checkout")
+	}
+	if *update && *baseline == "" {
+		rep.usage("--update-baseline needs --baseline=<file>")
+	}
+
+	cases, err := findSuiteCases(fs.Arg(0))
+	if err != nil {
+		rep.report(&Failure{Kind: KindIO, Message: err.Error(), File: fs.Arg(0)})
+		rep.exit()
+	}
+
+	var results []*suiteResult
+	for _, c := range cases {
+		results = append(results, runSuiteCase(c))
+	}
+
+	if *update {
+		if err := writeSuiteBaseline(*baseline, results); err != nil {
+			rep.report(&Failure{Kind: KindIO, Message: err.Error(), File: *baseline})
+			rep.exit()
+		}
+		printSuiteMatrix(os.Stdout, results, nil)
+		fmt.Printf("\nBaseline written to %s\n", *baseline)
+		return
+	}
+
+	var known map[string]map[string]string
+	if *baseline != "" {
+		known, err = readSuiteBaseline(*baseline)
+		if err != nil {
+			rep.report(&Failure{Kind: KindIO, Message: err.Error(), File: *baseline})
+			rep.exit()
+		}
+	}
+
+	if failed := printSuiteMatrix(os.Stdout, results, known); failed {
+		os.Exit(1)
+	}
+}
+
+// findSuiteCases returns the cases below dir in the order of their ids.
+// Cases with several tests keep each one in a numbered subdirectory, whose
+// id is the case id and the number, as in "2G84/00".
+func findSuiteCases(dir string) ([]suiteCase, error) {
+	var cases []suiteCase
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "in.yaml")); err != nil {
+			return nil
+		}
+
+		id, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		c := suiteCase{id: filepath.ToSlash(id), dir: path}
+		// The tests of a case may share the name of the case
+		for _, nameDir := range []string{path, filepath.Dir(path)} {
+			if name, err := os.ReadFile(filepath.Join(nameDir, "===")); err == nil {
+				c.name = strings.TrimSpace(string(name))
+				break
+			}
+		}
+		cases = append(cases, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the suite: %v", err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no test cases (directories with an in.yaml) found")
+	}
+	return cases, nil
+}
+
+// runSuiteCase makes every check that a case has expected results for. A
+// panic fails the check it happened in, and the checks after it are skipped,
+// so that one case cannot end the run.
+func runSuiteCase(c suiteCase) (result *suiteResult) {
+	result = &suiteResult{
+		id:      c.id,
+		name:    c.name,
+		results: make(map[string]string),
+		diffs:   make(map[string]string),
+	}
+	for _, check := range suiteChecks {
+		result.results[check] = suiteSkip
+	}
+	set := func(check, diff string) {
+		if diff == "" {
+			result.results[check] = suitePass
+			return
+		}
+		result.results[check] = suiteFail
+		result.diffs[check] = diff
+	}
+	current := "events"
+	defer func() {
+		if r := recover(); r != nil {
+			set(current, fmt.Sprintf("panic: %v\n", r))
+		}
+	}()
+
+	input, err := os.ReadFile(filepath.Join(c.dir, "in.yaml"))
+	if err != nil {
+		set("events", err.Error()+"\n")
+		return result
+	}
+	read := func(name string) (string, bool) {
+		data, err := os.ReadFile(filepath.Join(c.dir, name))
+		return string(data), err == nil
+	}
+
+	// Cases marked with an error file must fail to parse; the events
+	// before the error are not compared
+	events, eventsErr := suiteEvents(input)
+	if _, invalid := read("error"); invalid {
+		if eventsErr == nil {
+			set("events", "expected a parse error, got events:\n"+events)
+		} else {
+			set("events", "")
+		}
+		return result
+	}
+
+	if expected, ok := read("test.event"); ok {
+		if eventsErr != nil {
+			set("events", fmt.Sprintf("unexpected error: %v\n", eventsErr))
+		} else {
+			set("events", unifiedDiff("test.event", "go-yaml", expected, events))
+		}
+	}
+	if expected, ok := read("in.json"); ok {
+		current = "json"
+		set("json", checkSuiteJSON(input, expected))
+	}
+
+	// The re-emitted YAML must read back as the same data as the
+	// suite's own emitted YAML, or as the input where there is none
+	current = "yaml"
+	expected, ok := read("out.yaml")
+	if !ok {
+		expected = string(input)
+	}
+	set("yaml", checkSuiteYAML(input, []byte(expected)))
+	return result
+}
+
+// suiteEvents returns the yaml-test-suite events of input
+func suiteEvents(input []byte) (string, error) {
+	var out bytes.Buffer
+	err := ProcessSuiteEvents(bytes.NewReader(input), &out)
+	return out.String(), err
+}
+
+// checkSuiteJSON compares the JSON output for input with the expected
+// JSON stream, as values rather than as text
+func checkSuiteJSON(input []byte, expected string) string {
+	var out bytes.Buffer
+	if err := ProcessJSON(bytes.NewReader(input), &out, JSONOptions{}); err != nil {
+		return fmt.Sprintf("unexpected error: %v\n", err)
+	}
+
+	want, err := decodeJSONStream(expected)
+	if err != nil {
+		return fmt.Sprintf("cannot read in.json: %v\n", err)
+	}
+	got, err := decodeJSONStream(out.String())
+	if err != nil {
+		return fmt.Sprintf("cannot read the JSON output: %v\n", err)
+	}
+	if reflect.DeepEqual(want, got) {
+		return ""
+	}
+	return unifiedDiff("in.json", "go-yaml", indentJSON(want), indentJSON(got))
+}
+
+// decodeJSONStream decodes a stream of JSON values
+func decodeJSONStream(text string) ([]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	values := []interface{}{}
+	for {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			return values, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+}
+
+// indentJSON writes JSON values one per line in a canonical form for
+// diffing
+func indentJSON(values []interface{}) string {
+	var out strings.Builder
+	for _, value := range values {
+		data, _ := json.MarshalIndent(value, "", "  ")
+		out.Write(data)
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+// checkSuiteYAML re-emits input as YAML and compares the events of the
+// result with those of the expected YAML. Presentation, such as scalar
+// styles and document markers, is left out of the comparison.
+func checkSuiteYAML(input, expected []byte) string {
+	var out bytes.Buffer
+	if err := ProcessYAML(bytes.NewReader(input), &out, YAMLOptions{Preserve: true}); err != nil {
+		return fmt.Sprintf("unexpected error: %v\n", err)
+	}
+
+	want, err := suiteEvents(expected)
+	if err != nil {
+		return fmt.Sprintf("cannot read the expected YAML: %v\n", err)
+	}
+	got, err := suiteEvents(out.Bytes())
+	if err != nil {
+		return fmt.Sprintf("cannot read the emitted YAML: %v\n%s", err, out.String())
+	}
+	return unifiedDiff("expected", "go-yaml", contentEvents(want), contentEvents(got))
+}
+
+// contentEvents strips yaml-test-suite events down to the data they carry
+func contentEvents(events string) string {
+	var out strings.Builder
+	for _, line := range splitLines(events) {
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "+DOC ---":
+			line = "+DOC"
+		case line == "-DOC ...":
+			line = "-DOC"
+		case strings.HasPrefix(line, "+MAP {}"), strings.HasPrefix(line, "+SEQ []"):
+			line = line[:4] + line[7:]
+		case strings.HasPrefix(line, "=VAL "):
+			// Anchors and tags come before the style indicator
+			i := len("=VAL ")
+			for i < len(line) && (line[i] == '&' || line[i] == '<') {
+				i += strings.IndexByte(line[i:], ' ') + 1
+			}
+			line = line[:i] + ":" + line[i+1:]
+		}
+		out.WriteString(line + "\n")
+	}
+	return out.String()
+}
+
+// printSuiteMatrix writes a line per case with the result of each check,
+// then the diffs of the failing checks and a summary. With the results of
+// a baseline, checks that fail there are known failures: only regressions
+// get a diff and fail the run. It reports whether the run failed.
+func printSuiteMatrix(w io.Writer, results []*suiteResult, baseline map[string]map[string]string) bool {
+	fmt.Fprintf(w, "%-12s %-6s %-6s %-6s %s\n", "case", "events", "json", "yaml", "name")
+	for _, r := range results {
+		fmt.Fprintf(w, "%-12s %-6s %-6s %-6s %s", r.id, r.results["events"], r.results["json"], r.results["yaml"], r.name)
+		if baseline != nil {
+			if note := baselineNote(r, baseline[r.id]); note != "" {
+				fmt.Fprintf(w, "  [%s]", note)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	cases, failures, regressions, fixed := 0, 0, 0, 0
+	for _, r := range results {
+		cases++
+		failing := false
+		for _, check := range suiteChecks {
+			known := baseline[r.id][check]
+			switch {
+			case r.results[check] == suiteFail && known == suiteFail:
+				failing = true
+			case r.results[check] == suiteFail:
+				failing = true
+				regressions++
+				printSectionHeader(w, r.id+" "+check, false)
+				fmt.Fprint(w, r.diffs[check])
+			case r.results[check] == suitePass && known == suiteFail:
+				fixed++
+			}
+		}
+		if failing {
+			failures++
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d cases: %d passed, %d failed\n", cases, cases-failures, failures)
+	if baseline == nil {
+		return failures > 0
+	}
+	fmt.Fprintf(w, "Against the baseline: %d regressions, %d fixed\n", regressions, fixed)
+	return regressions > 0
+}
+
+// baselineNote marks a case whose results differ from its baseline results
+func baselineNote(r *suiteResult, known map[string]string) string {
+	if known == nil {
+		return "new"
+	}
+	note := ""
+	for _, check := range suiteChecks {
+		switch {
+		case r.results[check] == suiteFail && known[check] != suiteFail:
+			return "REGRESSED"
+		case r.results[check] == suitePass && known[check] == suiteFail:
+			note = "fixed"
+		}
+	}
+	return note
+}
+
+// writeSuiteBaseline writes the results as a baseline: a line per case
+// with its id and the result of each check
+func writeSuiteBaseline(name string, results []*suiteResult) error {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "# go-yaml suite baseline: case %s\n", strings.Join(suiteChecks, " "))
+	for _, r := range results {
+		fmt.Fprint(&out, r.id)
+		for _, check := range suiteChecks {
+			fmt.Fprint(&out, " "+r.results[check])
+		}
+		fmt.Fprintln(&out)
+	}
+	if err := os.WriteFile(name, out.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write baseline: %v", err)
+	}
+	return nil
+}
+
+// readSuiteBaseline reads the results of a baseline by case and check
+func readSuiteBaseline(name string) (map[string]map[string]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %v", err)
+	}
+	defer file.Close()
+
+	baseline := make(map[string]map[string]string)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != len(suiteChecks)+1 {
+			return nil, fmt.Errorf("bad baseline line %d: %q", line, text)
+		}
+		results := make(map[string]string)
+		for i, check := range suiteChecks {
+			results[check] = fields[i+1]
+		}
+		baseline[fields[0]] = results
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %v", err)
+	}
+	return baseline, nil
+}
+
+// printSuiteHelp displays the help information for the suite command
+func printSuiteHelp() {
+	fmt.Print(`Usage:
+  go-yaml suite [options] <dir>
+
+Runs go-yaml on every case of a yaml-test-suite checkout (the data branch)
+and prints a pass/fail matrix with a diff for each failing check. The checks
+compare the events with test.event (or expect a parse error where the case
+has an error file), the JSON with in.json and the re-emitted YAML with
+out.yaml. The exit code is 1 when a check fails, or with a baseline, when a
+check fails that passes in the baseline.
+
+Options:
+  --baseline=...    Results file to compare with
+  --update-baseline Write the results to the baseline file
+  --error-format    Error output format: text (default), json or yaml
+`)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSuiteCase writes the files of a yaml-test-suite case below dir
+func writeSuiteCase(t *testing.T, dir, id string, files map[string]string) {
+	t.Helper()
+	caseDir := filepath.Join(dir, id)
+	if err := os.MkdirAll(caseDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(caseDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestSuiteRunner tests the pass/fail matrix, the diffs of failing checks
+// and the comparison with a baseline
+func TestSuiteRunner(t *testing.T) {
+	dir := t.TempDir()
+	writeSuiteCase(t, dir, "229Q", map[string]string{
+		"===":        "Sequence of Mappings\n",
+		"in.yaml":    "-\n  name: Mark\n  hr:   65\n",
+		"test.event": "+STR\n+DOC\n+SEQ\n+MAP\n=VAL :name\n=VAL :Mark\n=VAL :hr\n=VAL :65\n-MAP\n-SEQ\n-DOC\n-STR\n",
+		"in.json":    `[{"name": "Mark", "hr": 65}]`,
+		"out.yaml":   "- name: Mark\n  hr: 65\n",
+	})
+	writeSuiteCase(t, dir, "2G84/00", map[string]string{
+		"in.yaml":    "--- |0\n",
+		"error":      "",
+		"test.event": "+STR\n+DOC ---\n",
+	})
+	writeSuiteCase(t, dir, "CT4Q", map[string]string{
+		"===":        "Wrong expectation",
+		"in.yaml":    "a: 1\n",
+		"test.event": "+STR\n+DOC\n+MAP\n=VAL :a\n=VAL :2\n-MAP\n-DOC\n-STR\n",
+		"in.json":    `{"a": 1}`,
+	})
+
+	stdout, stderr, err := runCommand("", "suite", dir)
+	if code := exitCode(err); code != 1 {
+		t.Errorf("Expected exit code 1, got %d (%s)", code, stderr)
+	}
+	expected := []string{
+		"229Q         pass   pass   pass   Sequence of Mappings\n",
+		"2G84/00      pass   -      -      \n",
+		"CT4Q         FAIL   pass   pass   Wrong expectation\n",
+		"=== CT4Q events ===\n--- test.event\n+++ go-yaml\n@@ -2,7 +2,7 @@\n",
+		"-=VAL :2\n+=VAL :1\n",
+		"3 cases: 2 passed, 1 failed\n",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+
+	// Known failures do not fail a run against a baseline
+	baseline := filepath.Join(t.TempDir(), "baseline")
+	if _, stderr, err := runCommand("", "suite", "--baseline="+baseline, "--update-baseline", dir); err != nil {
+		t.Fatalf("Expected no error, got %v (%s)", err, stderr)
+	}
+	stdout, _, err = runCommand("", "suite", "--baseline="+baseline, dir)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if strings.Contains(stdout, "=== CT4Q events ===") || !strings.Contains(stdout, "0 regressions, 0 fixed") {
+		t.Errorf("Expected no regressions, got %q", stdout)
+	}
+
+	// A check that passes in the baseline and fails now is a regression
+	writeSuiteCase(t, dir, "229Q", map[string]string{"in.json": `[{"name": "Sammy"}]`})
+	stdout, _, err = runCommand("", "suite", "--baseline="+baseline, dir)
+	if code := exitCode(err); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stdout, "Sequence of Mappings  [REGRESSED]") || !strings.Contains(stdout, "=== 229Q json ===") {
+		t.Errorf("Expected a regression, got %q", stdout)
+	}
+}
@@ -5,135 +5,219 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
-	"strings"
 
 	"go.yaml.in/yaml/v3"
 )
 
-// Token represents a YAML token with comment information
+// Token represents a YAML token produced by the go-yaml scanner
 type Token struct {
 	Type        string
 	Value       string
+	Suffix      string
+	Prefix      string
 	Style       string
 	StartLine   int
 	StartColumn int
+	StartIndex  int
+	StartOffset int
 	EndLine     int
 	EndColumn   int
-	HeadComment string
-	LineComment string
-	FootComment string
+	EndIndex    int
+	EndOffset   int
+	Comments    []*Comment
+}
+
+// Comment represents a comment record collected by the go-yaml scanner
+type Comment struct {
+	Kind        string
+	Text        string
+	StartLine   int
+	StartColumn int
+	StartIndex  int
+	StartOffset int
+	EndLine     int
+	EndColumn   int
+	EndIndex    int
+	EndOffset   int
 }
 
 // TokenInfo represents the information about a YAML token for YAML encoding
 type TokenInfo struct {
-	Token string `yaml:"Token"`
-	Value string `yaml:"Value,omitempty"`
-	Style string `yaml:"Style,omitempty"`
-	Head  string `yaml:"Head,omitempty"`
-	Line  string `yaml:"Line,omitempty"`
-	Foot  string `yaml:"Foot,omitempty"`
-	Pos   string `yaml:"Pos,omitempty"`
+	Token  string `yaml:"Token"`
+	Value  string `yaml:"Value,omitempty"`
+	Suffix string `yaml:"Suffix,omitempty"`
+	Prefix string `yaml:"Prefix,omitempty"`
+	Style  string `yaml:"Style,omitempty"`
+	Pos    string `yaml:"Pos,omitempty"`
+	Offset string `yaml:"Offset,omitempty"`
+	Index  string `yaml:"Index,omitempty"`
 }
 
-// ProcessTokens reads YAML from stdin and outputs token information using the internal scanner
-func ProcessTokens(profuse, compact bool) error {
-	decoder := yaml.NewDecoder(os.Stdin)
-	firstDoc := true
+// CommentInfo represents the information about a scanner comment for YAML
+// encoding
+type CommentInfo struct {
+	Comment  string `yaml:"Comment"`
+	Value    string `yaml:"Value"`
+	Attached string `yaml:"Attached"`
+	Pos      string `yaml:"Pos,omitempty"`
+	Offset   string `yaml:"Offset,omitempty"`
+	Index    string `yaml:"Index,omitempty"`
+}
+
+// ProcessTokens reads YAML from r and writes token information to w using the internal scanner
+func ProcessTokens(r io.Reader, w io.Writer, profuse, compact bool, style YAMLStyle) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return &kindError{kind: KindIO, err: fmt.Errorf("failed to read input: %v", err)}
+	}
+	offsets := NewOffsetMap(input)
+
+	parser, err := yaml.NewParser(bytes.NewReader(input))
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %v", err)
+	}
+	defer parser.Close()
 
 	for {
-		var node yaml.Node
-		err := decoder.Decode(&node)
+		yamlToken, err := parser.Next()
 		if err != nil {
-			if err == io.EOF {
-				break
+			// Report everything produced so far, then the error itself
+			if info := formatErrorInfo(err, offsets); info != nil {
+				if printErr := printErrorInfo(w, info, compact, style); printErr != nil {
+					return printErr
+				}
 			}
-			return fmt.Errorf("failed to decode YAML: %v", err)
+			return fmt.Errorf("failed to scan YAML: %w", err)
+		}
+		if yamlToken == nil {
+			break
 		}
 
-		// Add document separator for all documents except the first
-		if !firstDoc {
-			fmt.Println("---")
+		token := newToken(yamlToken, offsets)
+
+		// Comments are printed just before the token they are attached to
+		for _, comment := range token.Comments {
+			info := formatCommentInfo(comment, token, profuse)
+			if err := printCommentInfo(w, info, compact, style); err != nil {
+				return err
+			}
 		}
-		firstDoc = false
 
-		tokens := processNodeToTokens(&node, profuse)
+		info := formatTokenInfo(token, profuse)
+		if err := printTokenInfo(w, info, compact, style); err != nil {
+			return err
+		}
+	}
 
-		if compact {
-			// For compact mode, output each token as a flow style mapping in a sequence
-			for _, token := range tokens {
-				info := formatTokenInfo(token, profuse)
+	return nil
+}
 
-				// Create a YAML node with flow style for the mapping
-				compactNode := &yaml.Node{
-					Kind:  yaml.MappingNode,
-					Style: yaml.FlowStyle,
-				}
+// newToken converts a scanner token into a Token.
+// The scanner reports 0-based columns; they are made 1-based here to match
+// the positions shown for nodes.
+func newToken(t *yaml.Token, offsets *OffsetMap) *Token {
+	token := &Token{
+		Type:        t.Type,
+		Value:       t.Value,
+		Suffix:      t.Suffix,
+		Prefix:      t.Prefix,
+		Style:       t.Style,
+		StartLine:   t.StartLine,
+		StartColumn: t.StartCol + 1,
+		StartIndex:  t.StartIndex,
+		StartOffset: offsets.ByteOffset(t.StartIndex),
+		EndLine:     t.EndLine,
+		EndColumn:   t.EndCol + 1,
+		EndIndex:    t.EndIndex,
+		EndOffset:   offsets.ByteOffset(t.EndIndex),
+	}
+	if t.Type == "VERSION-DIRECTIVE" {
+		token.Value = fmt.Sprintf("%d.%d", t.Major, t.Minor)
+	}
+	for _, c := range t.Comments {
+		token.Comments = append(token.Comments, &Comment{
+			Kind:        c.Kind,
+			Text:        c.Text,
+			StartLine:   c.StartLine,
+			StartColumn: c.StartCol + 1,
+			StartIndex:  c.StartIndex,
+			StartOffset: offsets.ByteOffset(c.StartIndex),
+			EndLine:     c.EndLine,
+			EndColumn:   c.EndCol + 1,
+			EndIndex:    c.EndIndex,
+			EndOffset:   offsets.ByteOffset(c.EndIndex),
+		})
+	}
+	return token
+}
 
-				// Add the Token field
-				compactNode.Content = append(compactNode.Content,
-					&yaml.Node{Kind: yaml.ScalarNode, Value: "Token"},
-					&yaml.Node{Kind: yaml.ScalarNode, Value: info.Token})
+// printTokenInfo writes a single token as a one-item YAML sequence
+func printTokenInfo(w io.Writer, info *TokenInfo, compact bool, style YAMLStyle) error {
+	var buf bytes.Buffer
+	enc := newYAMLEncoder(&buf, style)
 
-				// Add other fields if they exist
-				if info.Value != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Value"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Value})
-				}
-				if info.Style != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Style"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Style})
-				}
-				if info.Head != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Head"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Head})
-				}
-				if info.Line != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Line"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Line})
-				}
-				if info.Foot != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Foot"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Foot})
-				}
-				if info.Pos != "" {
-					compactNode.Content = append(compactNode.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "Pos"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: info.Pos})
-				}
+	if compact {
+		// For compact mode, output each token as a flow style mapping in a sequence
+		compactNode := &yaml.Node{
+			Kind:  yaml.MappingNode,
+			Style: yaml.FlowStyle,
+		}
 
-				var buf bytes.Buffer
-				enc := yaml.NewEncoder(&buf)
-				enc.SetIndent(2)
-				if err := enc.Encode([]*yaml.Node{compactNode}); err != nil {
-					enc.Close()
-					return fmt.Errorf("failed to marshal compact token info: %v", err)
-				}
-				enc.Close()
-				fmt.Print(buf.String())
-			}
-		} else {
-			// For non-compact mode, output each token as a separate mapping
-			for _, token := range tokens {
-				info := formatTokenInfo(token, profuse)
+		// Add the Token field
+		compactNode.Content = append(compactNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "Token"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: info.Token})
 
-				var buf bytes.Buffer
-				enc := yaml.NewEncoder(&buf)
-				enc.SetIndent(2)
-				if err := enc.Encode([]*TokenInfo{info}); err != nil {
-					enc.Close()
-					return fmt.Errorf("failed to marshal token info: %v", err)
-				}
-				enc.Close()
-				fmt.Print(buf.String())
-			}
+		// Add other fields if they exist
+		if info.Value != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Value"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Value})
+		}
+		if info.Suffix != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Suffix"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Suffix})
+		}
+		if info.Prefix != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Prefix"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Prefix})
+		}
+		if info.Style != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Style"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Style})
+		}
+		if info.Pos != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Pos"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Pos})
+		}
+		if info.Offset != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Offset"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Offset})
+		}
+		if info.Index != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Index"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Index})
+		}
+
+		if err := enc.Encode([]*yaml.Node{compactNode}); err != nil {
+			enc.Close()
+			return &kindError{kind: KindEncode, err: fmt.Errorf("failed to marshal compact token info: %v", err)}
+		}
+	} else {
+		// For non-compact mode, output each token as a separate mapping
+		if err := enc.Encode([]*TokenInfo{info}); err != nil {
+			enc.Close()
+			return &kindError{kind: KindEncode, err: fmt.Errorf("failed to marshal token info: %v", err)}
 		}
 	}
+	enc.Close()
+	fmt.Fprint(w, buf.String())
 
 	return nil
 }
@@ -147,203 +231,90 @@ func formatTokenInfo(token *Token, profuse bool) *TokenInfo {
 	if token.Value != "" {
 		info.Value = token.Value
 	}
-	if token.Style != "" && token.Style != "Plain" {
-		info.Style = token.Style
-	}
-	if token.HeadComment != "" {
-		info.Head = token.HeadComment
+	if token.Suffix != "" {
+		info.Suffix = token.Suffix
 	}
-	if token.LineComment != "" {
-		info.Line = token.LineComment
+	if token.Prefix != "" {
+		info.Prefix = token.Prefix
 	}
-	if token.FootComment != "" {
-		info.Foot = token.FootComment
+	if token.Style != "" && token.Style != "Plain" {
+		info.Style = token.Style
 	}
 	if profuse {
-		if token.StartLine == token.EndLine && token.StartColumn == token.EndColumn {
-			info.Pos = fmt.Sprintf("%d;%d", token.StartLine, token.StartColumn)
-		} else {
-			info.Pos = fmt.Sprintf("%d;%d-%d;%d", token.StartLine, token.StartColumn, token.EndLine, token.EndColumn)
-		}
+		info.Pos = formatPos(token.StartLine, token.StartColumn, token.EndLine, token.EndColumn)
+		info.Offset = formatOffset(token.StartOffset, token.EndOffset)
+		info.Index = formatOffset(token.StartIndex, token.EndIndex)
 	}
 
 	return info
 }
 
-// processNodeToTokens converts a node to a slice of tokens
-func processNodeToTokens(node *yaml.Node, profuse bool) []*Token {
-	var tokens []*Token
-
-	// Add stream start token
-	tokens = append(tokens, &Token{
-		Type: "STREAM-START",
-	})
-
-	// Add document start token
-	tokens = append(tokens, &Token{
-		Type: "DOCUMENT-START",
-	})
-
-	// Process the node content
-	tokens = append(tokens, processNodeToTokensRecursive(node, profuse)...)
-
-	// Add document end token
-	tokens = append(tokens, &Token{
-		Type: "DOCUMENT-END",
-	})
-
-	// Add stream end token
-	tokens = append(tokens, &Token{
-		Type: "STREAM-END",
-	})
+// formatCommentInfo converts a Comment to a CommentInfo struct for YAML
+// encoding
+func formatCommentInfo(comment *Comment, token *Token, profuse bool) *CommentInfo {
+	info := &CommentInfo{
+		Comment:  comment.Kind,
+		Value:    comment.Text,
+		Attached: token.Type,
+	}
+	if profuse {
+		info.Pos = formatPos(comment.StartLine, comment.StartColumn, comment.EndLine, comment.EndColumn)
+		info.Offset = formatOffset(comment.StartOffset, comment.EndOffset)
+		info.Index = formatOffset(comment.StartIndex, comment.EndIndex)
+	}
 
-	return tokens
+	return info
 }
 
-// processNodeToTokensRecursive recursively converts a node to tokens
-func processNodeToTokensRecursive(node *yaml.Node, profuse bool) []*Token {
-	var tokens []*Token
+// printCommentInfo writes a single comment record as a one-item YAML sequence
+func printCommentInfo(w io.Writer, info *CommentInfo, compact bool, style YAMLStyle) error {
+	var buf bytes.Buffer
+	enc := newYAMLEncoder(&buf, style)
 
-	switch node.Kind {
-	case yaml.DocumentNode:
-		for _, child := range node.Content {
-			tokens = append(tokens, processNodeToTokensRecursive(child, profuse)...)
-		}
-	case yaml.MappingNode:
-		tokens = append(tokens, &Token{
-			Type:        "BLOCK-MAPPING-START",
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-			HeadComment: node.HeadComment,
-			LineComment: node.LineComment,
-			FootComment: node.FootComment,
-		})
-		for i := 0; i < len(node.Content); i += 2 {
-			if i+1 < len(node.Content) {
-				// Key
-				tokens = append(tokens, &Token{
-					Type:        "KEY",
-					StartLine:   node.Content[i].Line,
-					StartColumn: node.Content[i].Column,
-					EndLine:     node.Content[i].Line,
-					EndColumn:   node.Content[i].Column,
-				})
-				keyTokens := processNodeToTokensRecursive(node.Content[i], profuse)
-				tokens = append(tokens, keyTokens...)
-				// Value
-				tokens = append(tokens, &Token{
-					Type:        "VALUE",
-					StartLine:   node.Content[i+1].Line,
-					StartColumn: node.Content[i+1].Column,
-					EndLine:     node.Content[i+1].Line,
-					EndColumn:   node.Content[i+1].Column,
-				})
-				valueTokens := processNodeToTokensRecursive(node.Content[i+1], profuse)
-				tokens = append(tokens, valueTokens...)
-			}
-		}
-		tokens = append(tokens, &Token{
-			Type:        "BLOCK-END",
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-		})
-	case yaml.SequenceNode:
-		tokens = append(tokens, &Token{
-			Type:        "BLOCK-SEQUENCE-START",
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-			HeadComment: node.HeadComment,
-			LineComment: node.LineComment,
-			FootComment: node.FootComment,
-		})
-		for _, child := range node.Content {
-			tokens = append(tokens, &Token{
-				Type:        "BLOCK-ENTRY",
-				StartLine:   child.Line,
-				StartColumn: child.Column,
-				EndLine:     child.Line,
-				EndColumn:   child.Column,
-			})
-			childTokens := processNodeToTokensRecursive(child, profuse)
-			tokens = append(tokens, childTokens...)
+	if compact {
+		// For compact mode, output the comment as a flow style mapping in a sequence
+		compactNode := &yaml.Node{
+			Kind:  yaml.MappingNode,
+			Style: yaml.FlowStyle,
 		}
-		tokens = append(tokens, &Token{
-			Type:        "BLOCK-END",
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-		})
-	case yaml.ScalarNode:
-		// Check for anchor before the scalar
-		if node.Anchor != "" {
-			tokens = append(tokens, &Token{
-				Type:        "ANCHOR",
-				Value:       node.Anchor,
-				StartLine:   node.Line,
-				StartColumn: node.Column,
-				EndLine:     node.Line,
-				EndColumn:   node.Column,
-			})
+		compactNode.Content = append(compactNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "Comment"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: info.Comment},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "Value"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: info.Value},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "Attached"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: info.Attached})
+		if info.Pos != "" {
+			compactNode.Content = append(compactNode.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Pos"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Pos},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Offset"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Offset},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "Index"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: info.Index})
 		}
 
-		// Check for tag before the scalar
-		if node.Tag != "" && node.Tag != "!!str" && node.Tag != "!!int" && node.Tag != "!!float" && node.Tag != "!!bool" {
-			tokens = append(tokens, &Token{
-				Type:        "TAG",
-				Value:       node.Tag,
-				StartLine:   node.Line,
-				StartColumn: node.Column,
-				EndLine:     node.Line,
-				EndColumn:   node.Column,
-			})
+		if err := enc.Encode([]*yaml.Node{compactNode}); err != nil {
+			enc.Close()
+			return &kindError{kind: KindEncode, err: fmt.Errorf("failed to marshal compact comment info: %v", err)}
 		}
-
-		// Calculate end position for scalars based on value length
-		endLine := node.Line
-		endColumn := node.Column
-		if node.Value != "" {
-			// For single-line values, add the length to the column
-			if !strings.Contains(node.Value, "\n") {
-				endColumn += len(node.Value)
-			} else {
-				// For multi-line values, we'd need more complex logic
-				// For now, just use the start position
-				endColumn = node.Column
-			}
+	} else {
+		if err := enc.Encode([]*CommentInfo{info}); err != nil {
+			enc.Close()
+			return &kindError{kind: KindEncode, err: fmt.Errorf("failed to marshal comment info: %v", err)}
 		}
-		tokens = append(tokens, &Token{
-			Type:        "SCALAR",
-			Value:       node.Value,
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     endLine,
-			EndColumn:   endColumn,
-			Style:       formatStyle(node.Style),
-			HeadComment: node.HeadComment,
-			LineComment: node.LineComment,
-			FootComment: node.FootComment,
-		})
-	case yaml.AliasNode:
-		// Generate ALIAS token for alias nodes
-		tokens = append(tokens, &Token{
-			Type:        "ALIAS",
-			Value:       node.Value,
-			StartLine:   node.Line,
-			StartColumn: node.Column,
-			EndLine:     node.Line,
-			EndColumn:   node.Column,
-			HeadComment: node.HeadComment,
-			LineComment: node.LineComment,
-			FootComment: node.FootComment,
-		})
 	}
+	enc.Close()
+	fmt.Fprint(w, buf.String())
 
-	return tokens
+	return nil
+}
+
+// formatPos renders a start/end position as "line;col" or
+// "line;col-line;col"
+func formatPos(startLine, startColumn, endLine, endColumn int) string {
+	if startLine == endLine && startColumn == endColumn {
+		return fmt.Sprintf("%d;%d", startLine, startColumn)
+	}
+	return fmt.Sprintf("%d;%d-%d;%d", startLine, startColumn, endLine, endColumn)
 }
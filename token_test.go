@@ -22,6 +22,16 @@ func TestTokenMode(t *testing.T) {
 			"items:\n  - one\n  - two",
 			[]string{"Token: STREAM-START", "Token: BLOCK-MAPPING-START", "Token: BLOCK-SEQUENCE-START", "Token: BLOCK-ENTRY", "Value: one", "Value: two", "Token: BLOCK-END"},
 		},
+		{
+			"flow sequence",
+			"[1, 2, 3]",
+			[]string{"Token: FLOW-SEQUENCE-START", "Token: FLOW-ENTRY", "Token: FLOW-SEQUENCE-END"},
+		},
+		{
+			"flow mapping",
+			"{a: b}",
+			[]string{"Token: FLOW-MAPPING-START", "Token: KEY", "Token: VALUE", "Token: FLOW-MAPPING-END"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -87,3 +97,93 @@ settings:
 		}
 	}
 }
+
+// TestTokenModeNoBlockMappingForFlow tests that flow collections are not
+// reported as block collections
+func TestTokenModeNoBlockMappingForFlow(t *testing.T) {
+	stdout, stderr, err := runCommand("[1, 2, 3]", "-t")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no stderr, got %q", stderr)
+	}
+	if strings.Contains(stdout, "BLOCK-MAPPING-START") {
+		t.Errorf("Expected no BLOCK-MAPPING-START token, got %q", stdout)
+	}
+}
+
+// TestTokenProfusePositions tests that -T reports real scanner positions
+func TestTokenProfusePositions(t *testing.T) {
+	stdout, _, err := runCommand("a: [1, 2]", "-T")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := []string{
+		"{Token: FLOW-SEQUENCE-START, Pos: 1;4-1;5, ",
+		"{Token: FLOW-ENTRY, Pos: 1;6-1;7, ",
+		"{Token: FLOW-SEQUENCE-END, Pos: 1;9-1;10, ",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+}
+
+// TestTokenModeComments tests that scanner comment records are reported
+// before the token they are attached to
+func TestTokenModeComments(t *testing.T) {
+	input := "# head\na: 1 # line\nb:\n  - x\n  # foot\nc: 2\n"
+
+	stdout, stderr, err := runCommand(input, "-t")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no stderr, got %q", stderr)
+	}
+	expected := []string{
+		"- {Comment: Head, Value: '# head', Attached: STREAM-START}\n- {Token: STREAM-START}",
+		"- {Comment: Line, Value: '# line', Attached: SCALAR}\n- {Token: SCALAR, Value: 1}",
+		"- {Comment: Foot, Value: '# foot', Attached: SCALAR}\n- {Token: SCALAR, Value: x}",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+}
+
+// TestTokenProfuseCommentPositions tests that -T reports comment positions
+func TestTokenProfuseCommentPositions(t *testing.T) {
+	stdout, _, err := runCommand("a: 1 # line\n", "-T")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := "{Comment: Line, Value: '# line', Attached: SCALAR, Pos: 1;6, "
+	if !strings.Contains(stdout, expected) {
+		t.Errorf("Expected output to contain %q, got %q", expected, stdout)
+	}
+}
+
+// TestTokenModeDirectives tests that directive and tag token payloads are
+// shown
+func TestTokenModeDirectives(t *testing.T) {
+	input := "%YAML 1.1\n%TAG !e! tag:example.com,2000:\n--- !e!foo x\n"
+
+	stdout, _, err := runCommand(input, "-t")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := []string{
+		"{Token: VERSION-DIRECTIVE, Value: 1.1}",
+		"{Token: TAG-DIRECTIVE, Value: '!e!', Prefix: 'tag:example.com,2000:'}",
+		"{Token: TAG, Value: '!e!', Suffix: foo}",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, stdout)
+		}
+	}
+}
@@ -0,0 +1,543 @@
+// Package main provides YAML to TOML conversion utilities for the go-yaml tool.
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ProcessTOML reads a YAML document from r and writes its TOML encoding to w.
+// TOML has no null, no top-level values other than a table and no stream of
+// documents, so YAML using any of these is an error naming the path that
+// TOML cannot express. Nothing is written unless the whole input converts.
+func ProcessTOML(r io.Reader, w io.Writer, warn func(*Failure)) error {
+	decoder := yaml.NewDecoder(r)
+	var docs []*yaml.Node
+
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to decode YAML: %w", err)
+		}
+		docs = append(docs, &node)
+	}
+
+	if len(docs) > 1 {
+		return &conversionError{
+			kind:    KindEncode,
+			path:    pathRoot,
+			line:    docs[1].Line,
+			column:  docs[1].Column,
+			message: "TOML cannot represent more than one document",
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		converter := &tomlConverter{warn: warn}
+		table, err := converter.document(doc)
+		if err != nil {
+			return err
+		}
+		writeTOMLTable(&buf, table, nil)
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return &kindError{kind: KindIO, err: fmt.Errorf("failed to write TOML: %v", err)}
+	}
+	return nil
+}
+
+// tomlTable is a TOML table that keeps its keys in order
+type tomlTable []tomlEntry
+
+// tomlEntry is a single key of a tomlTable
+type tomlEntry struct {
+	key   string
+	value interface{}
+}
+
+// tomlDatetime is a TOML date or date-time, kept as its TOML text
+type tomlDatetime string
+
+// tomlConverter converts nodes into TOML values: tomlTables, slices,
+// strings, int64s, float64s, bools and tomlDatetimes. Aliases and merge keys
+// are followed as the JSON conversion follows them.
+type tomlConverter struct {
+	warn func(*Failure)
+	// aliases holds the alias targets being converted
+	aliases []*yaml.Node
+	aliased int
+}
+
+// lossy reports a lossy conversion of the node at path
+func (c *tomlConverter) lossy(path string, n *yaml.Node, format string, args ...interface{}) {
+	if c.warn == nil {
+		return
+	}
+	c.warn(&Failure{
+		Kind:    KindWarning,
+		Message: fmt.Sprintf(format, args...),
+		Path:    path,
+		Line:    n.Line,
+		Column:  n.Column,
+	})
+}
+
+// fail returns an error of the given kind for the node at path
+func (c *tomlConverter) fail(kind, path string, n *yaml.Node, format string, args ...interface{}) error {
+	return &conversionError{
+		kind:    kind,
+		path:    path,
+		line:    n.Line,
+		column:  n.Column,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+// document converts a document, whose root must be a mapping. An empty
+// document is an empty table.
+func (c *tomlConverter) document(doc *yaml.Node) (tomlTable, error) {
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind == yaml.AliasNode {
+		root = root.Alias
+	}
+	switch {
+	case root.Kind == yaml.ScalarNode && root.ShortTag() == "!!null":
+		return nil, nil
+	case root.Kind != yaml.MappingNode:
+		return nil, c.fail(KindEncode, pathRoot, root, "TOML document must be a table, not %s", kindName(root))
+	}
+	value, err := c.value(root, pathRoot)
+	if err != nil {
+		return nil, err
+	}
+	return value.(tomlTable), nil
+}
+
+// value converts the node at path
+func (c *tomlConverter) value(n *yaml.Node, path string) (interface{}, error) {
+	if len(c.aliases) > 0 {
+		c.aliased++
+		if c.aliased > maxAliasedNodes {
+			return nil, c.fail(KindCompose, path, n, "document contains excessive aliasing")
+		}
+	}
+
+	switch n.Kind {
+	case yaml.AliasNode:
+		return c.alias(n, path)
+	case yaml.SequenceNode:
+		return c.array(n, path)
+	case yaml.MappingNode:
+		var table tomlTable
+		if err := c.addEntries(&table, n, path); err != nil {
+			return nil, err
+		}
+		return table, nil
+	}
+	return c.scalar(n, path)
+}
+
+// alias converts the target of an alias node at path
+func (c *tomlConverter) alias(n *yaml.Node, path string) (interface{}, error) {
+	for _, target := range c.aliases {
+		if target == n.Alias {
+			return nil, c.fail(KindCompose, path, n, "anchor '%s' value contains itself", n.Value)
+		}
+	}
+	c.aliases = append(c.aliases, n.Alias)
+	defer func() { c.aliases = c.aliases[:len(c.aliases)-1] }()
+
+	return c.value(n.Alias, path)
+}
+
+// array converts a sequence node at path. The items of a TOML array must
+// all be of one type, as readers of TOML before 1.0 require.
+func (c *tomlConverter) array(n *yaml.Node, path string) (interface{}, error) {
+	items := make([]interface{}, 0, len(n.Content))
+	first := ""
+	for i, child := range n.Content {
+		itemPath := pathIndex(path, i)
+		item, err := c.value(child, itemPath)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			first = tomlType(item)
+		} else if itemType := tomlType(item); itemType != first {
+			return nil, c.fail(KindEncode, itemPath, child, "TOML array cannot mix %s and %s values", first, itemType)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// scalar converts a scalar node at path
+func (c *tomlConverter) scalar(n *yaml.Node, path string) (interface{}, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, c.fail(KindEncode, path, n, "TOML cannot represent null")
+	case "!!int":
+		var i int64
+		if err := n.Decode(&i); err != nil {
+			return nil, c.fail(KindEncode, path, n, "integer %s does not fit in a TOML integer", n.Value)
+		}
+		return i, nil
+	case "!!float":
+		if n.Tag == "!!float" && n.Style&yaml.TaggedStyle == 0 && bigInteger.MatchString(n.Value) {
+			return nil, c.fail(KindEncode, path, n, "integer %s does not fit in a TOML integer", n.Value)
+		}
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, c.decodeError(path, n, err)
+		}
+		return f, nil
+	case "!!binary":
+		text := strings.Join(strings.Fields(n.Value), "")
+		if _, err := base64.StdEncoding.DecodeString(text); err == nil {
+			c.lossy(path, n, "!!binary written as a base64 string")
+			return text, nil
+		}
+	}
+
+	var value interface{}
+	if err := n.Decode(&value); err != nil {
+		return nil, c.decodeError(path, n, err)
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil, c.fail(KindEncode, path, n, "TOML cannot represent null")
+	case string, bool, int64, float64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case uint64:
+		return nil, c.fail(KindEncode, path, n, "integer %s does not fit in a TOML integer", n.Value)
+	case time.Time:
+		return tomlTimestamp(n.Value, v), nil
+	}
+	return nil, c.fail(KindEncode, path, n, "TOML cannot represent %s", n.ShortTag())
+}
+
+// decodeError turns a go-yaml decoding error for the node at path into a
+// conversion error
+func (c *tomlConverter) decodeError(path string, n *yaml.Node, err error) error {
+	return c.fail(KindDecode, path, n, "%s", strings.TrimPrefix(err.Error(), "yaml: "))
+}
+
+// addEntries adds the entries of a mapping at path. Explicit keys replace
+// merged ones and merged keys never replace a key that is already present,
+// as with the JSON conversion.
+func (c *tomlConverter) addEntries(table *tomlTable, n *yaml.Node, path string) error {
+	lines := make(map[string]int)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keyNode, valueNode := n.Content[i], n.Content[i+1]
+
+		if keyNode.Kind == yaml.ScalarNode && keyNode.ShortTag() == "!!merge" {
+			if err := c.merge(table, valueNode, path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		keyPath := pathKey(path, keyNode)
+		key, err := c.key(keyNode, keyPath)
+		if err != nil {
+			return err
+		}
+		if line, ok := lines[key]; ok {
+			return c.fail(KindDecode, keyPath, keyNode, "mapping key %q already defined at line %d", key, line)
+		}
+		lines[key] = keyNode.Line
+
+		value, err := c.value(valueNode, keyPath)
+		if err != nil {
+			return err
+		}
+		if j := table.find(key); j >= 0 {
+			// Only merged keys can be present already
+			(*table)[j].value = value
+			continue
+		}
+		*table = append(*table, tomlEntry{key: key, value: value})
+	}
+	return nil
+}
+
+// merge adds the entries of the mappings named by a merge key
+func (c *tomlConverter) merge(table *tomlTable, n *yaml.Node, path string) error {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		var entries tomlTable
+		if err := c.addEntries(&entries, n, path); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if table.find(entry.key) < 0 {
+				*table = append(*table, entry)
+			}
+		}
+		return nil
+	case yaml.SequenceNode:
+		for _, child := range n.Content {
+			if err := c.merge(table, child, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return c.fail(KindDecode, path, n, "map merge requires map or sequence of maps as the value")
+}
+
+// key returns the TOML key of a mapping key node. TOML keys are strings,
+// so other scalars are written as their text.
+func (c *tomlConverter) key(keyNode *yaml.Node, path string) (string, error) {
+	if keyNode.Kind == yaml.AliasNode {
+		keyNode = keyNode.Alias
+	}
+	if keyNode.Kind != yaml.ScalarNode {
+		return "", c.fail(KindEncode, path, keyNode, "%s key is not a string", keyNode.ShortTag())
+	}
+	if tag := keyNode.ShortTag(); tag != "!!str" {
+		c.lossy(path, keyNode, "%s key written as the string %q", tag, keyNode.Value)
+	}
+	return keyNode.Value, nil
+}
+
+// find returns the index of the entry with the given key, or -1
+func (t tomlTable) find(key string) int {
+	for i, entry := range t {
+		if entry.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// kindName names the kind of a node for messages
+func kindName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		return "a sequence"
+	case yaml.MappingNode:
+		return "a mapping"
+	}
+	return "a " + n.ShortTag() + " scalar"
+}
+
+// dateOnly matches YAML timestamps without a time of day
+var dateOnly = regexp.MustCompile(`^[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}$`)
+
+// tomlTimestamp returns the TOML text of a YAML timestamp: a local date for
+// a date alone, otherwise an offset date-time
+func tomlTimestamp(text string, t time.Time) tomlDatetime {
+	if dateOnly.MatchString(text) {
+		return tomlDatetime(t.Format("2006-01-02"))
+	}
+	return tomlDatetime(t.Format(time.RFC3339Nano))
+}
+
+// tomlType names the TOML type of a converted value
+func tomlType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case bool:
+		return "boolean"
+	case tomlDatetime:
+		return "datetime"
+	case tomlTable:
+		return "table"
+	}
+	return "array"
+}
+
+// isTableArray reports whether a value is written as an array of tables
+func isTableArray(value interface{}) bool {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return false
+	}
+	_, ok = items[0].(tomlTable)
+	return ok
+}
+
+// writeTOMLTable writes the entries of the table named by keys. Plain
+// values come first, as TOML requires, followed by the sub-tables and
+// arrays of tables in document order.
+func writeTOMLTable(buf *bytes.Buffer, table tomlTable, keys []string) {
+	for _, entry := range table {
+		if _, ok := entry.value.(tomlTable); ok || isTableArray(entry.value) {
+			continue
+		}
+		fmt.Fprintf(buf, "%s = %s\n", tomlKey(entry.key), tomlInline(entry.value))
+	}
+
+	for _, entry := range table {
+		name := append(keys[:len(keys):len(keys)], entry.key)
+		switch value := entry.value.(type) {
+		case tomlTable:
+			// A header is only needed when the table has values of its
+			// own or would otherwise not appear at all
+			if hasPlainValues(value) || len(value) == 0 {
+				writeTOMLHeader(buf, "["+tomlKeyPath(name)+"]")
+			}
+			writeTOMLTable(buf, value, name)
+		case []interface{}:
+			if !isTableArray(value) {
+				continue
+			}
+			for _, item := range value {
+				writeTOMLHeader(buf, "[["+tomlKeyPath(name)+"]]")
+				writeTOMLTable(buf, item.(tomlTable), name)
+			}
+		}
+	}
+}
+
+// writeTOMLHeader writes a table header, separated from what precedes it by
+// a blank line
+func writeTOMLHeader(buf *bytes.Buffer, header string) {
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(header + "\n")
+}
+
+// hasPlainValues reports whether a table has entries written as key = value
+// under its own header
+func hasPlainValues(table tomlTable) bool {
+	for _, entry := range table {
+		if _, ok := entry.value.(tomlTable); !ok && !isTableArray(entry.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// bareKey matches keys that TOML allows without quotes
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKey returns a key, quoted when it is not a bare key
+func tomlKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlKeyPath returns the dotted name of a table
+func tomlKeyPath(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = tomlKey(key)
+	}
+	return strings.Join(quoted, ".")
+}
+
+// tomlInline returns the TOML text of a value written on one line
+func tomlInline(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return tomlString(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return tomlFloat(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case tomlDatetime:
+		return string(v)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = tomlInline(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case tomlTable:
+		if len(v) == 0 {
+			return "{}"
+		}
+		entries := make([]string, len(v))
+		for i, entry := range v {
+			entries[i] = tomlKey(entry.key) + " = " + tomlInline(entry.value)
+		}
+		return "{ " + strings.Join(entries, ", ") + " }"
+	}
+	return ""
+}
+
+// tomlFloat returns the TOML text of a float, which always has a fraction
+// or an exponent so that it reads back as a float
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	text := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(text, ".e") {
+		text += ".0"
+	}
+	return text
+}
+
+// tomlString returns a TOML basic string
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTOMLMode tests that tables, arrays of tables and scalars are written
+// as TOML in document order
+func TestTOMLMode(t *testing.T) {
+	input := `title: Example
+owner:
+  name: Tom
+  dob: 1979-05-27T07:32:00-08:00
+  born: 1979-05-27
+ports: [8000, 8001]
+ratio: 1
+limit: .inf
+products:
+  - name: Hammer
+  - name: Nail
+"a key": "x\"y"
+`
+	expected := `title = "Example"
+ports = [8000, 8001]
+ratio = 1
+limit = inf
+"a key" = "x\"y"
+
+[owner]
+name = "Tom"
+dob = 1979-05-27T07:32:00-08:00
+born = 1979-05-27
+
+[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Nail"
+`
+
+	for _, args := range [][]string{{"toml"}, {"--toml"}} {
+		stdout, stderr, err := runCommand(input, args...)
+		if err != nil {
+			t.Errorf("%v: Expected no error, got %v (%s)", args, err, stderr)
+		}
+		if stdout != expected {
+			t.Errorf("%v: Expected %q, got %q", args, expected, stdout)
+		}
+	}
+}
+
+// TestTOMLErrors tests that YAML that TOML cannot express is an encode
+// error naming its path
+func TestTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"top-level sequence", "- a\n- b", "$: TOML document must be a table, not a sequence"},
+		{"null value", "a:\n  b: null", "<stdin>:2:6: encode error in document 0: $.a.b: TOML cannot represent null"},
+		{"mixed array", "a: [1, x]", "$.a[1]: TOML array cannot mix integer and string values"},
+		{"big integer", "a: 99999999999999999999", "$.a: integer 99999999999999999999 does not fit in a TOML integer"},
+		{"several documents", "a: 1\n---\nb: 2", "$: TOML cannot represent more than one document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCommand(tt.input, "toml")
+			if code := exitCode(err); code != 8 {
+				t.Errorf("Expected exit code 8, got %d", code)
+			}
+			if stdout != "" {
+				t.Errorf("Expected no output, got %q", stdout)
+			}
+			if !strings.Contains(stderr, tt.expected) {
+				t.Errorf("Expected %q in stderr, got %q", tt.expected, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,449 @@
+// Package main provides the output views and subcommands of the go-yaml tool.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// view is one way of showing an input, such as its tokens or its JSON.
+// Views report lossy conversions through warn. With check or diff the
+// output is compared with the input instead of being shown, and with inPlace
+// it replaces the input file, saving a copy under the backup suffix if set.
+type view struct {
+	label   string
+	what    string
+	process func(r io.Reader, w io.Writer, warn func(*Failure)) error
+	check   bool
+	diff    bool
+	inPlace bool
+	backup  string
+}
+
+// viewOptions holds the flags that shape the views of a run
+type viewOptions struct {
+	profuse   bool
+	long      bool
+	resolved  bool
+	expand    bool
+	annotate  bool
+	suite     bool
+	pretty    bool
+	lines     bool
+	array     bool
+	preserve  bool
+	normalize bool
+	restyle   string
+	check     bool
+	diff      bool
+	inPlace   bool
+	backup    string
+	keys      string
+	floats    string
+	flow      bool
+	quote     string
+	style     YAMLStyle
+}
+
+// viewNames lists the subcommands in pipeline order
+var viewNames = []string{"tokens", "events", "nodes", "json", "toml", "yaml", "from-json", "from-toml"}
+
+// commandFlags lists the subcommand flags and the views that use them
+var commandFlags = []struct {
+	short, long, usage string
+	views              []string
+}{
+	{"p", "profuse", "Show line info", []string{"tokens", "events", "nodes"}},
+	{"l", "long", "Long (block) formatted output", []string{"tokens", "events"}},
+	{"r", "resolved", "Show resolved tags of all nodes", []string{"nodes"}},
+	{"x", "expand", "Expand alias targets inline", []string{"nodes"}},
+	{"a", "annotate", "Annotate source lines with spans", []string{"tokens", "events"}},
+	{"", "suite", "yaml-test-suite event notation", []string{"events"}},
+	{"", "pretty", "Pretty JSON output", []string{"json"}},
+	{"", "jsonl", "One compact JSON document per line", []string{"json"}},
+	{"", "json-array", "Wrap all documents in one JSON array", []string{"json"}},
+	{"", "preserve", "Preserve YAML style and comments", []string{"yaml"}},
+	{"", "normalize", "Keep key order and anchors, drop comments and styles", []string{"yaml"}},
+	{"", "check", "Fail when re-emitting the input would change it", []string{"yaml"}},
+	{"", "diff", "Show the changes re-emitting would make as a diff", []string{"yaml"}},
+	{"i", "in-place", "Rewrite the files with the YAML output", []string{"yaml"}},
+	{"", "flow", "Flow style mappings and sequences", []string{"from-json"}},
+}
+
+// commandOptions lists the subcommand options that take a value and the
+// views that use them
+var commandOptions = []struct {
+	name, usage string
+	views       []string
+}{
+	{"keys", "Non-string mapping keys: error, stringify or pairs", []string{"json"}},
+	{"floats", "Infinity and NaN: error, string or null", []string{"json"}},
+	{"quote", "Quoting of strings: auto, single or double", []string{"from-json"}},
+	{"backup", "Suffix of the backup of each file --in-place rewrites", []string{"yaml"}},
+	{"restyle", "Style rules: [path=]double|single|plain|literal|flow|block,...", []string{"yaml"}},
+	{"indent", "Spaces per YAML indentation level: 2 to 9", yamlViews},
+	{"width", "Preferred YAML line width (0: no limit)", yamlViews},
+	{"seq-indent", "YAML sequence entries: indented or flush", yamlViews},
+}
+
+// yamlViews lists the views that write YAML
+var yamlViews = []string{"tokens", "events", "nodes", "yaml", "from-json", "from-toml"}
+
+// newView returns the named view configured by opts
+func newView(name string, opts viewOptions) (view, error) {
+	compact := !opts.long // compact is default, long mode negates it
+	v := view{label: name}
+	switch name {
+	case "tokens":
+		v.what = "tokens"
+		v.process = func(r io.Reader, w io.Writer, warn func(*Failure)) error {
+			if opts.annotate {
+				return ProcessAnnotatedTokens(r, w)
+			}
+			return ProcessTokens(r, w, opts.profuse, compact, opts.style)
+		}
+	case "events":
+		v.what = "events"
+		v.process = func(r io.Reader, w io.Writer, warn func(*Failure)) error {
+			if opts.suite {
+				return ProcessSuiteEvents(r, w)
+			}
+			if opts.annotate {
+				return ProcessAnnotatedEvents(r, w)
+			}
+			return ProcessEvents(r, w, opts.profuse, compact, opts.style)
+		}
+	case "nodes":
+		v.what = "nodes"
+		v.process = func(r io.Reader, w io.Writer, warn func(*Failure)) error {
+			return ProcessNodes(r, w, opts.profuse, opts.resolved, opts.expand, opts.style)
+		}
+	case "json":
+		v.what = "JSON"
+		v.process = func(r io.Reader, w io.Writer, warn func(*Failure)) error {
+			return ProcessJSON(r, w, JSONOptions{
+				Pretty: opts.pretty,
+				Lines:  opts.lines,
+				Array:  opts.array,
+				Keys:   opts.keys,
+				Floats: opts.floats,
+				Warn:   warn,
+			})
+		}
+	case "toml":
+		v.what = "TOML"
+		v.process = func(r io.Reader, w io.Writer, warn func(*Failure)) error {
+			return ProcessTOML(r, w, warn)
+		}
+	case "yaml":
+		v.what = "YAML"
+		v.process = func(r io.Reader, w io.Writer, warn func(*Failure)) error {
+			return ProcessYAML(r, w, YAMLOptions{Preserve: opts.preserve, Normalize: opts.normalize, Restyle: opts.restyle, Style: opts.style})
+		}
+		v.check = opts.check
+		v.diff = opts.diff
+		v.inPlace = opts.inPlace
+		v.backup = opts.backup
+	case "from-json":
+		v.what = "JSON input"
+		v.process = func(r io.Reader, w io.Writer, warn func(*Failure)) error {
+			return ProcessFromJSON(r, w, FromJSONOptions{Flow: opts.flow, Quote: opts.quote, Style: opts.style})
+		}
+	case "from-toml":
+		v.what = "TOML input"
+		v.process = func(r io.Reader, w io.Writer, warn func(*Failure)) error {
+			return ProcessFromTOML(r, w, opts.style, warn)
+		}
+	default:
+		return view{}, fmt.Errorf("unknown command %q", name)
+	}
+	return v, nil
+}
+
+// isCommand reports whether arg names a subcommand or a comma separated
+// list of them
+func isCommand(arg string) bool {
+	for _, name := range strings.Split(arg, ",") {
+		if _, err := newView(name, viewOptions{}); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// runSubcommand runs a subcommand such as `go-yaml tokens -p file.yaml`. A
+// comma separated list of subcommands shows each view of the same input in
+// its own section.
+func runSubcommand(command string, args []string) {
+	names := strings.Split(command, ",")
+
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.Usage = func() { printCommandHelp(command) }
+	set := make(map[string]*bool)
+	for _, f := range commandFlags {
+		set[f.long] = fs.Bool(f.long, false, f.usage)
+		if f.short != "" {
+			fs.BoolVar(set[f.long], f.short, false, f.usage)
+		}
+	}
+	values := make(map[string]*string)
+	for _, o := range commandOptions {
+		values[o.name] = fs.String(o.name, "", o.usage)
+	}
+	errorFormat := fs.String("error-format", "text", "Error output format: json, yaml or text")
+	fs.Parse(args)
+
+	rep, err := newReporter(*errorFormat)
+	if err != nil {
+		rep = &reporter{format: "text"}
+		rep.usage(err.Error())
+	}
+
+	// Flags that no requested view uses are an error rather than being
+	// silently ignored
+	setFlags := make(map[string]bool)
+	for name, value := range set {
+		setFlags[name] = *value
+	}
+	setValues := make(map[string]string)
+	for name, value := range values {
+		setValues[name] = *value
+	}
+	if err := checkUnusedFlags(names, setFlags, setValues); err != nil {
+		rep.usage(err.Error())
+	}
+	if err := checkAnnotateOptions(*set["annotate"], *set["suite"], *set["profuse"], *set["long"]); err != nil {
+		rep.usage(err.Error())
+	}
+
+	opts := viewOptions{
+		profuse:   *set["profuse"],
+		long:      *set["long"],
+		resolved:  *set["resolved"],
+		expand:    *set["expand"],
+		annotate:  *set["annotate"],
+		suite:     *set["suite"],
+		pretty:    *set["pretty"],
+		lines:     *set["jsonl"],
+		array:     *set["json-array"],
+		preserve:  *set["preserve"],
+		normalize: *set["normalize"],
+		restyle:   *values["restyle"],
+		check:     *set["check"],
+		diff:      *set["diff"],
+		inPlace:   *set["in-place"],
+		backup:    *values["backup"],
+		keys:      *values["keys"],
+		floats:    *values["floats"],
+		flow:      *set["flow"],
+		quote:     *values["quote"],
+	}
+	if err := checkFromJSONOptions(FromJSONOptions{Quote: opts.quote}); err != nil {
+		rep.usage(err.Error())
+	}
+	opts.style, err = parseYAMLStyle(*values["indent"], *values["width"], *values["seq-indent"])
+	if err != nil {
+		rep.usage(err.Error())
+	}
+	rep.style = opts.style
+	if err := checkYAMLOptions(YAMLOptions{Preserve: opts.preserve, Normalize: opts.normalize, Restyle: opts.restyle}); err != nil {
+		rep.usage(err.Error())
+	}
+	if err := checkJSONOptions(JSONOptions{Pretty: opts.pretty, Lines: opts.lines, Array: opts.array, Keys: opts.keys, Floats: opts.floats}); err != nil {
+		rep.usage(err.Error())
+	}
+	var views []view
+	for _, name := range names {
+		v, err := newView(name, opts)
+		if err != nil {
+			rep.usage(err.Error())
+		}
+		views = append(views, v)
+	}
+
+	files, err := expandFileArgs(fs.Args())
+	if err != nil {
+		rep.usage(err.Error())
+	}
+	if err := checkInPlace(opts.inPlace, opts.backup, opts.check || opts.diff, files); err != nil {
+		rep.usage(err.Error())
+	}
+	if err := checkInPlaceViews(views); err != nil {
+		rep.usage(err.Error())
+	}
+
+	runViews(views, files, rep)
+	rep.exit()
+}
+
+// checkUnusedFlags returns an error for the first flag set in flags, or
+// option set in values, that none of the named views uses. Both are keyed by
+// the long names of commandFlags and commandOptions.
+func checkUnusedFlags(names []string, flags map[string]bool, values map[string]string) error {
+	for _, f := range commandFlags {
+		if flags[f.long] && !usesFlag(names, f.views) {
+			return fmt.Errorf("--%s does not apply to %s", f.long, strings.Join(names, ","))
+		}
+	}
+	for _, o := range commandOptions {
+		if values[o.name] != "" && !usesFlag(names, o.views) {
+			return fmt.Errorf("--%s does not apply to %s", o.name, strings.Join(names, ","))
+		}
+	}
+	return nil
+}
+
+// checkAnnotateOptions rejects options that change the output --annotate or
+// --suite replaces. Both take the place of the token and event listings, so
+// line info and --long would be silently dropped, as would --annotate under
+// --suite.
+func checkAnnotateOptions(annotate, suite, profuse, long bool) error {
+	switch {
+	case suite && annotate:
+		return fmt.Errorf("--suite cannot be used with --annotate")
+	case suite && (profuse || long):
+		return fmt.Errorf("--suite cannot be used with line info or --long")
+	case annotate && (profuse || long):
+		return fmt.Errorf("--annotate cannot be used with line info or --long")
+	}
+	return nil
+}
+
+// usesFlag reports whether any of the named views is among views
+func usesFlag(names, views []string) bool {
+	for _, name := range names {
+		for _, v := range views {
+			if name == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runViews shows every view of every input, reporting failures to rep.
+// Each input is read once and shared by its views. Without files stdin is
+// read.
+func runViews(views []view, files []string, rep *reporter) {
+	if len(files) == 0 {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			rep.report(&Failure{Kind: KindIO, Message: fmt.Sprintf("failed to read stdin: %v", err)})
+			return
+		}
+		showViews(views, input, "", rep)
+		return
+	}
+
+	// Keep going after a failing file so that one bad file does not hide
+	// the results of the others. Checks, diffs and rewrites name the file
+	// themselves.
+	headers := len(files) > 1 && showsOutput(views)
+	for i, file := range files {
+		if headers {
+			printFileHeader(os.Stdout, file, i == 0)
+		}
+		input, err := readInput(file)
+		if err != nil {
+			rep.report(&Failure{Kind: KindIO, Message: err.Error(), File: file})
+			continue
+		}
+		showViews(views, input, file, rep)
+	}
+}
+
+// showsOutput reports whether any of the views writes its output to stdout
+// rather than comparing it with the input or rewriting the input file
+func showsOutput(views []view) bool {
+	for _, v := range views {
+		if !v.check && !v.diff && !v.inPlace {
+			return true
+		}
+	}
+	return false
+}
+
+// showViews shows every view of one input
+func showViews(views []view, input []byte, file string, rep *reporter) {
+	var source *sourceIndex
+	warn := func(f *Failure) {
+		if source == nil {
+			source = indexSource(input)
+		}
+		f.File = file
+		addSourceContext(f, input, source)
+		rep.warn(f)
+	}
+	for i, v := range views {
+		if len(views) > 1 {
+			printSectionHeader(os.Stdout, v.label, i == 0)
+		}
+		var out io.Writer = os.Stdout
+		var formatted bytes.Buffer
+		if v.check || v.diff || v.inPlace {
+			out = &formatted
+		}
+		if err := v.process(bytes.NewReader(input), out, warn); err != nil {
+			failure := newFailure(err, input)
+			failure.File = file
+			rep.report(failure)
+			continue
+		}
+		if v.check || v.diff {
+			if failure := compareFormatted(os.Stdout, input, formatted.Bytes(), file, v.check, v.diff); failure != nil {
+				if source == nil {
+					source = indexSource(input)
+				}
+				addSourceContext(failure, input, source)
+				rep.report(failure)
+			}
+		}
+		if v.inPlace {
+			if err := rewriteFile(file, input, formatted.Bytes(), v.backup); err != nil {
+				failure := &Failure{Kind: KindIO, Message: fmt.Sprintf("failed to rewrite file: %v", err)}
+				var kindErr *kindError
+				if errors.As(err, &kindErr) {
+					failure = newFailure(err, input)
+				}
+				failure.File = file
+				rep.report(failure)
+			}
+		}
+	}
+}
+
+// printSectionHeader writes the label that precedes each view's output when
+// several views are shown
+func printSectionHeader(w io.Writer, label string, first bool) {
+	if !first {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "=== %s ===\n", label)
+}
+
+// printCommandHelp displays the help information for a subcommand
+func printCommandHelp(command string) {
+	fmt.Printf("Usage:\n  go-yaml %s [options] [file|glob...]\n\nOptions:\n", command)
+	names := strings.Split(command, ",")
+	for _, f := range commandFlags {
+		if !usesFlag(names, f.views) {
+			continue
+		}
+		if f.short != "" {
+			fmt.Printf("  -%s, --%-10s %s\n", f.short, f.long, f.usage)
+		} else {
+			fmt.Printf("  --%-14s %s\n", f.long, f.usage)
+		}
+	}
+	for _, o := range commandOptions {
+		if usesFlag(names, o.views) {
+			fmt.Printf("  --%-14s %s\n", o.name+"=...", o.usage)
+		}
+	}
+	fmt.Printf("  --%-14s %s\n", "error-format", "Error output format: text (default), json or yaml")
+}
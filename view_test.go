@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSubcommands tests each subcommand and its options
+func TestSubcommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"tokens", []string{"tokens"}, "- {Token: STREAM-START}\n"},
+		{"tokens profuse", []string{"tokens", "-p"}, "Pos: 1;1-1;4"},
+		{"tokens long", []string{"tokens", "--long"}, "- Token: STREAM-START\n"},
+		{"events", []string{"events"}, "- {Event: SCALAR, Value: key}\n"},
+		{"nodes", []string{"nodes"}, "kind: Document"},
+		{"nodes profuse", []string{"nodes", "--profuse"}, "pos: 1;1"},
+		{"json", []string{"json"}, "{\"key\":\"value\"}\n"},
+		{"json pretty", []string{"json", "--pretty"}, "{\n  \"key\": \"value\"\n}\n"},
+		{"yaml", []string{"yaml"}, "key: value\n"},
+		{"yaml preserve", []string{"yaml", "--preserve"}, "key: value # note\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCommand("key: value # note\n", tt.args...)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if stderr != "" {
+				t.Errorf("Expected no stderr, got %q", stderr)
+			}
+			if !strings.Contains(stdout, tt.expected) {
+				t.Errorf("Expected output to contain %q, got %q", tt.expected, stdout)
+			}
+		})
+	}
+}
+
+// TestSeveralViews tests that several views of stdin are shown in labeled
+// sections, both for command lists and for combined mode flags
+func TestSeveralViews(t *testing.T) {
+	stdout, _, err := runCommand("a: 1\n", "json,tokens")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	expected := "=== json ===\n{\"a\":1}\n\n=== tokens ===\n- {Token: STREAM-START}\n"
+	if !strings.HasPrefix(stdout, expected) {
+		t.Errorf("Expected output to start with %q, got %q", expected, stdout)
+	}
+
+	stdout, _, err = runCommand("a: 1\n", "-j", "-t", "-E")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	for _, label := range []string{"=== token ===\n", "\n\n=== EVENT ===\n", "\n\n=== json ===\n"} {
+		if !strings.Contains(stdout, label) {
+			t.Errorf("Expected output to contain %q, got %q", label, stdout)
+		}
+	}
+	if strings.Index(stdout, "=== token ===") > strings.Index(stdout, "=== json ===") {
+		t.Errorf("Expected mode flags to be shown in pipeline order, got %q", stdout)
+	}
+}
+
+// TestSubcommandRejectsUnusedFlags tests that options no requested view
+// uses are reported instead of being ignored
+func TestSubcommandRejectsUnusedFlags(t *testing.T) {
+	_, stderr, err := runCommand("a: 1\n", "json", "-p")
+	if err == nil {
+		t.Errorf("Expected an error, got none")
+	}
+	if !strings.Contains(stderr, "--profuse does not apply to json") {
+		t.Errorf("Expected error message, got %q", stderr)
+	}
+
+	_, _, err = runCommand("a: 1\n", "json,nodes", "-p", "--pretty")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// The mode flags reject the same options
+	for _, tt := range []struct {
+		flags    []string
+		expected string
+	}{
+		{[]string{"-j", "-a"}, "--annotate does not apply to json"},
+		{[]string{"-j", "--flow"}, "--flow does not apply to json"},
+		{[]string{"-y", "--keys=pairs"}, "--keys does not apply to yaml"},
+		{[]string{"-e", "-r"}, "--resolved does not apply to events"},
+	} {
+		_, stderr, err := runCommand("a: 1\n", tt.flags...)
+		if code := exitCode(err); code != 2 {
+			t.Errorf("%v: Expected exit code 2, got %d", tt.flags, code)
+		}
+		if !strings.Contains(stderr, tt.expected) {
+			t.Errorf("%v: Expected %q, got %q", tt.flags, tt.expected, stderr)
+		}
+	}
+
+	_, _, err = runCommand("a: 1\n", "-J", "-n", "-r", "--keys=pairs")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+// TestAnnotateRejectsReplacedOptions tests that --annotate and --suite are
+// not combined with the options of the output they replace
+func TestAnnotateRejectsReplacedOptions(t *testing.T) {
+	for _, tt := range []struct {
+		flags    []string
+		expected string
+	}{
+		{[]string{"tokens", "-a", "-p"}, "--annotate cannot be used with line info or --long"},
+		{[]string{"events", "-a", "-l"}, "--annotate cannot be used with line info or --long"},
+		{[]string{"events", "--suite", "-p"}, "--suite cannot be used with line info or --long"},
+		{[]string{"events", "--suite", "-a"}, "--suite cannot be used with --annotate"},
+		{[]string{"-T", "-a"}, "--annotate cannot be used with line info or --long"},
+		{[]string{"-E", "--suite"}, "--suite cannot be used with line info or --long"},
+		{[]string{"--suite", "-l"}, "--suite cannot be used with line info or --long"},
+	} {
+		_, stderr, err := runCommand("a: 1\n", tt.flags...)
+		if code := exitCode(err); code != 2 {
+			t.Errorf("%v: Expected exit code 2, got %d", tt.flags, code)
+		}
+		if !strings.Contains(stderr, tt.expected) {
+			t.Errorf("%v: Expected %q, got %q", tt.flags, tt.expected, stderr)
+		}
+	}
+}
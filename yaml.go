@@ -4,16 +4,116 @@ package main
 import (
 	"fmt"
 	"io"
-	"os"
+	"strconv"
 
 	"go.yaml.in/yaml/v3"
 )
 
-// ProcessYAML reads YAML from stdin and outputs formatted YAML
-func ProcessYAML(preserve bool) error {
-	if preserve {
-		// Preserve comments and styles by using yaml.Node
-		decoder := yaml.NewDecoder(os.Stdin)
+// YAMLStyle is the layout of the YAML the tool writes, shared by every mode
+type YAMLStyle struct {
+	// Indent is the number of spaces per nesting level; zero means 2
+	Indent int
+	// Width is the preferred line width, beyond which scalars are folded;
+	// zero means lines are never folded
+	Width int
+	// FlushSequences writes the "- " of block sequence entries in the
+	// column of their mapping key instead of indenting them
+	FlushSequences bool
+}
+
+// parseYAMLStyle builds a YAMLStyle from the --indent, --width and
+// --seq-indent option values, where an empty value keeps the default
+func parseYAMLStyle(indent, width, seqIndent string) (YAMLStyle, error) {
+	var style YAMLStyle
+	if indent != "" {
+		n, err := strconv.Atoi(indent)
+		if err != nil || n < 2 || n > 9 {
+			return style, fmt.Errorf("bad indent %q (use 2 to 9 spaces)", indent)
+		}
+		style.Indent = n
+	}
+	if width != "" {
+		n, err := strconv.Atoi(width)
+		if err != nil || n < 0 {
+			return style, fmt.Errorf("bad width %q (use a number of columns, or 0 for no limit)", width)
+		}
+		// go-yaml ignores widths this small and uses 80 instead
+		if n > 0 && n <= 2*style.indent() {
+			return style, fmt.Errorf("width %d must be more than twice the indent", n)
+		}
+		style.Width = n
+	}
+	switch seqIndent {
+	case "", "indented":
+	case "flush":
+		style.FlushSequences = true
+	default:
+		return style, fmt.Errorf("unknown sequence indent %q (use indented or flush)", seqIndent)
+	}
+	return style, nil
+}
+
+// indent returns the number of spaces per nesting level
+func (s YAMLStyle) indent() int {
+	if s.Indent == 0 {
+		return 2
+	}
+	return s.Indent
+}
+
+// newYAMLEncoder returns an encoder that writes YAML to w laid out by style
+func newYAMLEncoder(w io.Writer, style YAMLStyle) *yaml.Encoder {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(style.indent())
+	if style.Width > 0 {
+		encoder.SetWidth(style.Width)
+	}
+	if style.FlushSequences {
+		encoder.FlushSeqIndent()
+	}
+	return encoder
+}
+
+// YAMLOptions controls the YAML written for YAML input
+type YAMLOptions struct {
+	// Preserve keeps the key order, anchors, styles and comments of the
+	// input
+	Preserve bool
+	// Normalize keeps the key order and anchors of the input but drops its
+	// comments and writes every scalar in its canonical style
+	Normalize bool
+	// Restyle is a comma separated list of style rules, each a style or
+	// path=style, applied to the node tree of Preserve and Normalize
+	Restyle string
+	// Style is the layout of the YAML written
+	Style YAMLStyle
+}
+
+// checkYAMLOptions validates the combination of modes in opts
+func checkYAMLOptions(opts YAMLOptions) error {
+	if opts.Preserve && opts.Normalize {
+		return fmt.Errorf("--preserve and --normalize cannot be used together")
+	}
+	if opts.Restyle != "" && !opts.Preserve && !opts.Normalize {
+		return fmt.Errorf("--restyle needs the node tree of --preserve or --normalize")
+	}
+	_, err := parseStyleRules(opts.Restyle)
+	return err
+}
+
+// ProcessYAML reads YAML from r and writes formatted YAML to w. By default
+// the documents are decoded into plain values, so keys are sorted and aliases
+// expanded; opts.Preserve and opts.Normalize work on the node tree instead.
+func ProcessYAML(r io.Reader, w io.Writer, opts YAMLOptions) error {
+	style := opts.Style
+	if opts.Preserve || opts.Normalize {
+		rules, err := parseStyleRules(opts.Restyle)
+		if err != nil {
+			return &kindError{kind: KindUsage, err: err}
+		}
+
+		// Keep key order and anchors by using yaml.Node
+		decoder := yaml.NewDecoder(r)
 		firstDoc := true
 
 		for {
@@ -23,12 +123,12 @@ func ProcessYAML(preserve bool) error {
 				if err == io.EOF || err.Error() == "EOF" {
 					break
 				}
-				return fmt.Errorf("failed to decode YAML: %v", err)
+				return fmt.Errorf("failed to decode YAML: %w", err)
 			}
 
 			// Add document separator for all documents except the first
 			if !firstDoc {
-				fmt.Println("---")
+				fmt.Fprintln(w, "---")
 			}
 			firstDoc = false
 
@@ -42,18 +142,18 @@ func ProcessYAML(preserve bool) error {
 					Content: []*yaml.Node{&node},
 				}
 			}
+			if opts.Normalize {
+				normalizeNode(outNode)
+			}
+			restyle(outNode, rules, style.Width)
 
-			encoder := yaml.NewEncoder(os.Stdout)
-			encoder.SetIndent(2)
-			if err := encoder.Encode(outNode); err != nil {
-				encoder.Close()
-				return fmt.Errorf("failed to encode YAML: %v", err)
+			if err := encodeYAML(w, outNode, style); err != nil {
+				return err
 			}
-			encoder.Close()
 		}
 	} else {
 		// Don't preserve comments and styles - use interface{} for clean output
-		decoder := yaml.NewDecoder(os.Stdin)
+		decoder := yaml.NewDecoder(r)
 		firstDoc := true
 
 		for {
@@ -63,24 +163,63 @@ func ProcessYAML(preserve bool) error {
 				if err == io.EOF || err.Error() == "EOF" {
 					break
 				}
-				return fmt.Errorf("failed to decode YAML: %v", err)
+				return fmt.Errorf("failed to decode YAML: %w", err)
 			}
 
 			// Add document separator for all documents except the first
 			if !firstDoc {
-				fmt.Println("---")
+				fmt.Fprintln(w, "---")
 			}
 			firstDoc = false
 
-			encoder := yaml.NewEncoder(os.Stdout)
-			encoder.SetIndent(2)
-			if err := encoder.Encode(data); err != nil {
-				encoder.Close()
-				return fmt.Errorf("failed to encode YAML: %v", err)
+			if err := encodeYAML(w, data, style); err != nil {
+				return err
 			}
-			encoder.Close()
 		}
 	}
 
 	return nil
 }
+
+// normalizeNode drops the comments of node and everything below it, and
+// resets scalar styles so that the encoder picks plain style where it can.
+// Tags that only restate what a scalar resolves to are dropped with the
+// style; other tags are kept.
+func normalizeNode(node *yaml.Node) {
+	node.HeadComment = ""
+	node.LineComment = ""
+	node.FootComment = ""
+	switch node.Kind {
+	case yaml.ScalarNode:
+		node.Style = 0
+		// Quote strings like "yes" so that the output matches -y
+		if node.ShortTag() == "!!str" && needsQuotes(node.Value) {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+	case yaml.AliasNode:
+		// The anchored node is normalized where it is defined
+		return
+	}
+	for _, child := range node.Content {
+		normalizeNode(child)
+	}
+}
+
+// needsQuotes reports whether go-yaml quotes a string when it writes it as a
+// value, as it does for strings that YAML 1.1 reads as another type, such as
+// yes or 07:32:00. A plain string node with such a value is written without
+// quotes, so the nodes the tool builds must ask for them.
+func needsQuotes(value string) bool {
+	out, err := yaml.Marshal(value)
+	return err == nil && out[0] == '"'
+}
+
+// encodeYAML writes a value as a YAML document laid out by style
+func encodeYAML(w io.Writer, v interface{}, style YAMLStyle) error {
+	encoder := newYAMLEncoder(w, style)
+	if err := encoder.Encode(v); err != nil {
+		encoder.Close()
+		return &kindError{kind: KindEncode, err: fmt.Errorf("failed to encode YAML: %v", err)}
+	}
+	return encoder.Close()
+}
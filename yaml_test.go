@@ -239,3 +239,109 @@ settings:
 		}
 	}
 }
+
+// TestYAMLStyleOptions tests --indent, --width and --seq-indent in the YAML
+// and inspection modes
+func TestYAMLStyleOptions(t *testing.T) {
+	input := "a:\n  b:\n  - x\n  - c: 1\ns: the quick brown fox jumps over the lazy dog\n"
+	tests := []struct {
+		name     string
+		flags    []string
+		expected string
+	}{
+		{
+			"indent",
+			[]string{"yaml", "--indent=4"},
+			"a:\n    b:\n        - x\n        - c: 1\ns: the quick brown fox jumps over the lazy dog\n",
+		},
+		{
+			"flush sequences",
+			[]string{"yaml", "--seq-indent=flush"},
+			"a:\n  b:\n  - x\n  - c: 1\ns: the quick brown fox jumps over the lazy dog\n",
+		},
+		{
+			"flush sequences with indent",
+			[]string{"-Y", "--indent=4", "--seq-indent=flush"},
+			"a:\n    b:\n    - x\n    - c: 1\ns: the quick brown fox jumps over the lazy dog\n",
+		},
+		{
+			"width",
+			[]string{"yaml", "--width=20"},
+			"a:\n  b:\n    - x\n    - c: 1\ns: the quick brown fox\n  jumps over the lazy\n  dog\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCommand(input, tt.flags...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v: %s", err, stderr)
+			}
+			if stdout != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, stdout)
+			}
+		})
+	}
+
+	// The inspection modes lay out their YAML the same way
+	stdout, _, err := runCommand("a: 1", "nodes", "--indent=4")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout, "content:\n    - kind: Mapping\n      content:\n") {
+		t.Errorf("Expected 4-space node output, got %q", stdout)
+	}
+}
+
+// TestYAMLStyleOptionErrors tests that bad style options are usage errors
+func TestYAMLStyleOptionErrors(t *testing.T) {
+	tests := []struct {
+		flags    []string
+		expected string
+	}{
+		{[]string{"yaml", "--indent=1"}, `bad indent "1"`},
+		{[]string{"-y", "--width=wide"}, `bad width "wide"`},
+		{[]string{"yaml", "--indent=4", "--width=8"}, "width 8 must be more than twice the indent"},
+		{[]string{"yaml", "--seq-indent=deep"}, `unknown sequence indent "deep"`},
+		{[]string{"json", "--indent=4"}, "--indent does not apply to json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.flags, " "), func(t *testing.T) {
+			_, stderr, err := runCommand("a: 1", tt.flags...)
+			if code := exitCode(err); code != 2 {
+				t.Errorf("Expected exit code 2, got %d", code)
+			}
+			if !strings.Contains(stderr, tt.expected) {
+				t.Errorf("Expected %q in stderr, got %q", tt.expected, stderr)
+			}
+		})
+	}
+}
+
+// TestYAMLNormalize tests that --normalize keeps key order and anchors but
+// drops comments and scalar styles
+func TestYAMLNormalize(t *testing.T) {
+	input := "# head\nzeta: 'single'  # line\nalpha: &a\n  - \"x\"\n  - !!str 12\n  - {b: 'q', a: \"yes\"}\nbeta: *a\n"
+	expected := "zeta: single\nalpha: &a\n  - x\n  - \"12\"\n  - {b: q, a: \"yes\"}\nbeta: *a\n"
+
+	for _, flags := range [][]string{{"yaml", "--normalize"}, {"--normalize"}} {
+		t.Run(strings.Join(flags, " "), func(t *testing.T) {
+			stdout, stderr, err := runCommand(input, flags...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v: %s", err, stderr)
+			}
+			if stdout != expected {
+				t.Errorf("Expected %q, got %q", expected, stdout)
+			}
+		})
+	}
+
+	_, stderr, err := runCommand(input, "-Y", "--normalize")
+	if code := exitCode(err); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(stderr, "--preserve and --normalize cannot be used together") {
+		t.Errorf("Expected usage error, got %q", stderr)
+	}
+}